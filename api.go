@@ -0,0 +1,119 @@
+package clabot
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/go-github/v58/github"
+)
+
+// Client is the subset of the GitHub API clabot uses, grouped like the
+// services of a *github.Client. Wrap a real client with FromGitHub, serve it
+// from GitLab with newGitLabClient, or fill the fields with fakes in tests.
+type Client struct {
+	Checks        checksAPI
+	Issues        issuesAPI
+	Organizations organizationsAPI
+	PullRequests  pullRequestsAPI
+	RateLimit     rateLimitAPI
+	Repositories  repositoriesAPI
+	Search        searchAPI
+	Teams         teamsAPI
+	Users         usersAPI
+
+	loginOnce sync.Once
+	login     string // see tokenLogin
+
+	gitlab *gitlabAPI // set by newGitLabClient; see newForge
+}
+
+// FromGitHub adapts a go-github client.
+func FromGitHub(gh *github.Client) *Client {
+	return &Client{
+		Checks:        gh.Checks,
+		Issues:        gh.Issues,
+		Organizations: gh.Organizations,
+		PullRequests:  gh.PullRequests,
+		RateLimit:     gh.RateLimit,
+		Repositories:  gh.Repositories,
+		Search:        gh.Search,
+		Teams:         gh.Teams,
+		Users:         gh.Users,
+	}
+}
+
+type checksAPI interface {
+	CreateCheckRun(ctx context.Context, owner, repo string, opts github.CreateCheckRunOptions) (*github.CheckRun, *github.Response, error)
+	ListCheckRunsForRef(ctx context.Context, owner, repo, ref string, opts *github.ListCheckRunsOptions) (*github.ListCheckRunsResults, *github.Response, error)
+	UpdateCheckRun(ctx context.Context, owner, repo string, checkRunID int64, opts github.UpdateCheckRunOptions) (*github.CheckRun, *github.Response, error)
+}
+
+type issuesAPI interface {
+	AddLabelsToIssue(ctx context.Context, owner, repo string, number int, labels []string) ([]*github.Label, *github.Response, error)
+	RemoveLabelForIssue(ctx context.Context, owner, repo string, number int, label string) (*github.Response, error)
+	CreateComment(ctx context.Context, owner, repo string, number int, comment *github.IssueComment) (*github.IssueComment, *github.Response, error)
+	EditComment(ctx context.Context, owner, repo string, commentID int64, comment *github.IssueComment) (*github.IssueComment, *github.Response, error)
+	DeleteComment(ctx context.Context, owner, repo string, commentID int64) (*github.Response, error)
+	ListComments(ctx context.Context, owner, repo string, number int, opts *github.IssueListCommentsOptions) ([]*github.IssueComment, *github.Response, error)
+}
+
+type organizationsAPI interface {
+	IsMember(ctx context.Context, org, user string) (bool, *github.Response, error)
+}
+
+type pullRequestsAPI interface {
+	Get(ctx context.Context, owner, repo string, number int) (*github.PullRequest, *github.Response, error)
+	List(ctx context.Context, owner, repo string, opts *github.PullRequestListOptions) ([]*github.PullRequest, *github.Response, error)
+	ListCommits(ctx context.Context, owner, repo string, number int, opts *github.ListOptions) ([]*github.RepositoryCommit, *github.Response, error)
+	ListFiles(ctx context.Context, owner, repo string, number int, opts *github.ListOptions) ([]*github.CommitFile, *github.Response, error)
+}
+
+type rateLimitAPI interface {
+	Get(ctx context.Context) (*github.RateLimits, *github.Response, error)
+}
+
+type repositoriesAPI interface {
+	Get(ctx context.Context, owner, repo string) (*github.Repository, *github.Response, error)
+	CreateStatus(ctx context.Context, owner, repo, ref string, status *github.RepoStatus) (*github.RepoStatus, *github.Response, error)
+	ListStatuses(ctx context.Context, owner, repo, ref string, opts *github.ListOptions) ([]*github.RepoStatus, *github.Response, error)
+	GetCommitSHA1(ctx context.Context, owner, repo, ref, lastSHA string) (string, *github.Response, error)
+	GetContents(ctx context.Context, owner, repo, path string, opts *github.RepositoryContentGetOptions) (*github.RepositoryContent, []*github.RepositoryContent, *github.Response, error)
+	CreateFile(ctx context.Context, owner, repo, path string, opts *github.RepositoryContentFileOptions) (*github.RepositoryContentResponse, *github.Response, error)
+	UpdateFile(ctx context.Context, owner, repo, path string, opts *github.RepositoryContentFileOptions) (*github.RepositoryContentResponse, *github.Response, error)
+	GetPermissionLevel(ctx context.Context, owner, repo, user string) (*github.RepositoryPermissionLevel, *github.Response, error)
+}
+
+type searchAPI interface {
+	Users(ctx context.Context, query string, opts *github.SearchOptions) (*github.UsersSearchResult, *github.Response, error)
+}
+
+type teamsAPI interface {
+	ListTeamMembersBySlug(ctx context.Context, org, slug string, opts *github.TeamListTeamMembersOptions) ([]*github.User, *github.Response, error)
+	GetTeamMembershipBySlug(ctx context.Context, org, slug, user string) (*github.Membership, *github.Response, error)
+}
+
+type usersAPI interface {
+	Get(ctx context.Context, user string) (*github.User, *github.Response, error)
+}
+
+// paginate collects every page of a list call, following Response.NextPage
+// from opts. page must pass opts (or the options embedding it) to the API.
+// It stops between pages once ctx is done, so a long listing can't outlive
+// the run.
+func paginate[T any](ctx context.Context, opts *github.ListOptions, page func(*github.ListOptions) ([]T, *github.Response, error)) ([]T, error) {
+	var all []T
+	for {
+		items, resp, err := page(opts)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		opts.Page = resp.NextPage
+	}
+}
@@ -0,0 +1,99 @@
+package clabot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-github/v58/github"
+)
+
+func TestPaginate(t *testing.T) {
+	for _, n := range []int{0, 1, 99, 100, 101, 250} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			all := make([]int, n)
+			for i := range all {
+				all[i] = i
+			}
+			calls := 0
+			got, err := paginate(context.Background(), &github.ListOptions{PerPage: 100}, func(opts *github.ListOptions) ([]int, *github.Response, error) {
+				calls++
+				items, resp := page(all, opts)
+				return items, resp, nil
+			})
+			if err != nil {
+				t.Fatalf("paginate: %v", err)
+			}
+			if len(got) != n {
+				t.Fatalf("got %d items, want %d", len(got), n)
+			}
+			for i, v := range got {
+				if v != i {
+					t.Fatalf("item %d = %d, pages out of order", i, v)
+				}
+			}
+			if want := max((n+99)/100, 1); calls != want {
+				t.Errorf("%d calls, want %d", calls, want)
+			}
+		})
+	}
+}
+
+func TestPaginateStopsOnError(t *testing.T) {
+	_, err := paginate(context.Background(), &github.ListOptions{PerPage: 1}, func(opts *github.ListOptions) ([]int, *github.Response, error) {
+		if opts.Page > 1 {
+			return nil, nil, fmt.Errorf("page %d failed", opts.Page)
+		}
+		return []int{1}, &github.Response{NextPage: 2}, nil
+	})
+	if err == nil {
+		t.Fatal("paginate swallowed the second page's error")
+	}
+}
+
+func TestPaginateStopsWhenDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	_, err := paginate(ctx, &github.ListOptions{PerPage: 1}, func(opts *github.ListOptions) ([]int, *github.Response, error) {
+		calls++
+		cancel()
+		return []int{1}, &github.Response{NextPage: opts.Page + 2}, nil
+	})
+	if err != context.Canceled || calls != 1 {
+		t.Errorf("paginate = %v after %d calls, want context.Canceled after 1", err, calls)
+	}
+}
+
+func TestAllCommitsFollowsPages(t *testing.T) {
+	f := newFakeGitHub()
+	for i := range 250 {
+		f.commits = append(f.commits, commit(fmt.Sprintf("user%d", i), fmt.Sprintf("user%d@example.com", i)))
+	}
+	commits, err := allCommits(context.Background(), f.client(), Config{}, 7)
+	if err != nil {
+		t.Fatalf("allCommits: %v", err)
+	}
+	if len(commits) != 250 {
+		t.Fatalf("got %d commits, want 250", len(commits))
+	}
+	if got := commits[249].GetAuthor().GetLogin(); got != "user249" {
+		t.Errorf("last commit by %s, want user249", got)
+	}
+}
+
+func TestAllFilesFollowsPages(t *testing.T) {
+	f := newFakeGitHub()
+	for i := range 3000 {
+		f.prFiles = append(f.prFiles, &github.CommitFile{Filename: github.String(fmt.Sprintf("docs/%d.md", i))})
+	}
+	files, err := allFiles(context.Background(), f.client(), Config{}, 7)
+	if err != nil {
+		t.Fatalf("allFiles: %v", err)
+	}
+	if len(files) != 3000 {
+		t.Fatalf("got %d files, want 3000", len(files))
+	}
+	if got := files[2999].GetFilename(); got != "docs/2999.md" {
+		t.Errorf("last file %s, want docs/2999.md", got)
+	}
+}
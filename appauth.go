@@ -0,0 +1,115 @@
+package clabot
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"golang.org/x/oauth2"
+)
+
+// appTokenSource mints installation access tokens for a GitHub App. Wrap it
+// in oauth2.ReuseTokenSource so tokens are only refreshed once they expire.
+type appTokenSource struct {
+	c              Config // for the API endpoint
+	appID          int64
+	installationID int64
+	key            *rsa.PrivateKey
+}
+
+func newAppTokenSource(c Config, appID, installationID int64, rawKey string) (*appTokenSource, error) {
+	key, err := parseAppKey(rawKey)
+	if err != nil {
+		return nil, err
+	}
+	return &appTokenSource{c: c, appID: appID, installationID: installationID, key: key}, nil
+}
+
+// parseAppKey accepts a PEM encoded RSA key, or the same PEM base64 encoded
+// so it can be stored in a single-line secret.
+func parseAppKey(raw string) (*rsa.PrivateKey, error) {
+	data := []byte(strings.TrimSpace(raw))
+	if !strings.Contains(raw, "-----BEGIN") {
+		decoded, err := base64.StdEncoding.DecodeString(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("private key is neither PEM nor base64: %w", err)
+		}
+		data = decoded
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("private key: no PEM block found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not RSA")
+	}
+	return key, nil
+}
+
+// jwt returns a short-lived token authenticating as the app itself.
+func (a *appTokenSource) jwt(now time.Time) (string, error) {
+	enc := base64.RawURLEncoding
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(), // allow for clock drift
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(a.appID, 10),
+	})
+
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
+
+// appClient returns a client authenticated as the app itself, which can only
+// call the /app endpoints.
+func (a *appTokenSource) appClient(ctx context.Context) (*github.Client, error) {
+	jwt, err := a.jwt(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return withEndpoint(github.NewClient(oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: jwt}))), a.c)
+}
+
+// Token exchanges an app JWT for an installation access token.
+func (a *appTokenSource) Token() (*oauth2.Token, error) {
+	ctx := context.Background()
+	appClient, err := a.appClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	it, _, err := appClient.Apps.CreateInstallationToken(ctx, a.installationID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create installation token: %w", err)
+	}
+
+	return &oauth2.Token{
+		AccessToken: it.GetToken(),
+		Expiry:      it.GetExpiresAt().Time,
+	}, nil
+}
@@ -0,0 +1,156 @@
+package clabot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"github.com/rs/zerolog/log"
+)
+
+// auditEntry is one CLA decision as written to the audit trail.
+type auditEntry struct {
+	Time          time.Time `json:"time"`
+	Repo          string    `json:"repo"`
+	PR            int       `json:"pr"`
+	SHA           string    `json:"sha"`
+	Author        string    `json:"author"`
+	Result        string    `json:"result"` // signed, unsigned, exempt, overridden, deferred or error
+	Description   string    `json:"description"`
+	SignerSources []string  `json:"signer_sources,omitempty"`
+	Actor         string    `json:"actor,omitempty"` // commenter who triggered the check
+	DryRun        bool      `json:"dry_run,omitempty"`
+}
+
+type actorKey struct{}
+
+// withActor records who triggered the run with a comment.
+func withActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func actorFrom(ctx context.Context) string {
+	a, _ := ctx.Value(actorKey{}).(string)
+	return a
+}
+
+// signerSources lists where the signers for a check on ref come from.
+func signerSources(c Config, ref string) []string {
+	switch c.Mode {
+	case modeDCO:
+		return nil
+	case modeCheckbox:
+		return []string{"pr-description"}
+	}
+	ref = signersFileRef(c, ref)
+	if ref == "" {
+		ref = "HEAD"
+	}
+	prefix := ""
+	if c.SignersRepo != "" {
+		prefix = c.SignersRepo + ":"
+	}
+	var out []string
+	out = append(out, c.GoogleSheetUrl...)
+	out = append(out, c.SignersURL...)
+	for _, p := range c.SignersPath {
+		out = append(out, prefix+p+"@"+ref)
+	}
+	out = append(out, c.SignersFileLocal...)
+	return out
+}
+
+// auditCheck records the outcome of a check on pr: signed or unsigned as
+// CheckResult has it, whatever status REPORT_ONLY posted, exempt when nobody
+// needed to sign, or error. Decisions that leave the check pending count
+// too: contributors still unsigned within GRACE_PERIOD are unsigned, and a
+// draft deferred by SKIP_DRAFTS is deferred.
+func auditCheck(ctx context.Context, c Config, pr *github.PullRequest, res CheckResult, err error) {
+	result, desc := res.State.String(), res.Description
+	switch {
+	case err != nil:
+		result, desc = "error", err.Error()
+	case res.Exempt:
+		result = "exempt"
+	case len(res.UnsignedLogins) > 0:
+		result = ResultUnsigned.String()
+	case res.State == ResultNone:
+		result = "deferred"
+	}
+	audit(ctx, c, pr, result, desc)
+}
+
+// audit appends the decision on pr to AUDIT_LOG_PATH and posts it to
+// AUDIT_LOG_URL, whichever are set. The file is synced before returning so
+// the record survives the process exiting right after.
+func audit(ctx context.Context, c Config, pr *github.PullRequest, result, description string) {
+	if c.AuditLogPath == "" && c.AuditLogURL == "" {
+		return
+	}
+	line, err := json.Marshal(auditEntry{
+		Time:          time.Now().UTC(),
+		Repo:          c.RepoOwner + "/" + c.RepoName,
+		PR:            pr.GetNumber(),
+		SHA:           pr.GetHead().GetSHA(),
+		Author:        pr.GetUser().GetLogin(),
+		Result:        result,
+		Description:   description,
+		SignerSources: signerSources(c, pr.GetBase().GetRef()),
+		Actor:         actorFrom(ctx),
+		DryRun:        c.DryRun,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to encode audit entry")
+		return
+	}
+	line = append(line, '\n')
+
+	if c.AuditLogPath != "" {
+		if err := appendSynced(c.AuditLogPath, line); err != nil {
+			log.Error().Err(err).Str("path", c.AuditLogPath).Msg("Failed to write audit log")
+		}
+	}
+	if c.AuditLogURL != "" {
+		if err := postAudit(ctx, c, line); err != nil {
+			log.Error().Err(err).Str("url", c.AuditLogURL).Msg("Failed to send audit entry")
+		}
+	}
+}
+
+func appendSynced(path string, line []byte) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func postAudit(ctx context.Context, c Config, line []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.AuditLogURL, bytes.NewReader(line))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := externalClient(c).Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("audit sink returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package clabot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"github.com/rs/zerolog/log"
+)
+
+// signerCache persists loaded signer sets between runs on long-lived
+// runners. Sheets are refetched once their entry is older than the TTL;
+// repo files additionally whenever their blob SHA changes. Every failure is
+// logged and treated as a cache miss, so the cache can only save work.
+type signerCache struct {
+	path    string
+	ttl     time.Duration
+	entries map[string]cacheEntry
+	dirty   bool
+}
+
+type cacheEntry struct {
+	Fetched time.Time `json:"fetched"`
+	SHA     string    `json:"sha,omitempty"` // blob SHA for repo files
+	Logins  []string  `json:"logins"`
+	Emails  []string  `json:"emails"`
+	Domains []string  `json:"domains,omitempty"`
+	Teams   []string  `json:"teams,omitempty"`
+	Exempt  []string  `json:"exempt,omitempty"`
+
+	Versions map[string]string `json:"versions,omitempty"`
+}
+
+// openSignerCache returns nil when caching is disabled. A nil cache is safe
+// to use and never hits.
+func openSignerCache(c Config) *signerCache {
+	if c.SignersCacheTTL <= 0 {
+		return nil
+	}
+
+	p := c.SignersCachePath
+	if p == "" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			log.Warn().Err(err).Msg("No cache directory, signer cache disabled")
+			return nil
+		}
+		p = filepath.Join(dir, "clabot", "signers.json")
+	}
+
+	sc := &signerCache{path: p, ttl: c.SignersCacheTTL, entries: make(map[string]cacheEntry)}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Warn().Err(err).Str("path", p).Msg("Failed to read signer cache")
+		}
+		return sc
+	}
+	if err := json.Unmarshal(data, &sc.entries); err != nil {
+		log.Warn().Err(err).Str("path", p).Msg("Ignoring corrupt signer cache")
+		sc.entries = make(map[string]cacheEntry)
+	}
+	return sc
+}
+
+// get returns the cached set for key if it is fresh and, when sha is
+// non-empty, was cached for the same blob.
+func (sc *signerCache) get(key, sha string) (SignerSet, bool) {
+	if sc == nil {
+		return SignerSet{}, false
+	}
+	e, ok := sc.entries[key]
+	if !ok || time.Since(e.Fetched) > sc.ttl || e.SHA != sha {
+		return SignerSet{}, false
+	}
+
+	s := NewSignerSet()
+	for _, l := range e.Logins {
+		s.Logins[l] = struct{}{}
+	}
+	for _, m := range e.Emails {
+		s.Emails[m] = struct{}{}
+	}
+	for _, d := range e.Domains {
+		s.Domains[d] = struct{}{}
+	}
+	for _, t := range e.Teams {
+		s.Teams[t] = struct{}{}
+	}
+	for _, l := range e.Exempt {
+		s.Exempt[l] = struct{}{}
+	}
+	for k, v := range e.Versions {
+		s.Versions[k] = v
+	}
+	log.Info().Str("key", key).Time("fetched", e.Fetched).Msg("Using cached signers")
+	return s, true
+}
+
+func (sc *signerCache) put(key, sha string, s SignerSet) {
+	if sc == nil {
+		return
+	}
+	e := cacheEntry{Fetched: time.Now(), SHA: sha, Versions: s.Versions}
+	for l := range s.Logins {
+		e.Logins = append(e.Logins, l)
+	}
+	for m := range s.Emails {
+		e.Emails = append(e.Emails, m)
+	}
+	for d := range s.Domains {
+		e.Domains = append(e.Domains, d)
+	}
+	for t := range s.Teams {
+		e.Teams = append(e.Teams, t)
+	}
+	for l := range s.Exempt {
+		e.Exempt = append(e.Exempt, l)
+	}
+	sc.entries[key] = e
+	sc.dirty = true
+}
+
+// clear drops every entry and returns the keys it dropped.
+func (sc *signerCache) clear() []string {
+	if sc == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(sc.entries))
+	for k := range sc.entries {
+		keys = append(keys, k)
+	}
+	sc.entries = make(map[string]cacheEntry)
+	sc.dirty = true
+	return keys
+}
+
+// save writes the cache back if anything changed.
+func (sc *signerCache) save() {
+	if sc == nil || !sc.dirty {
+		return
+	}
+	data, err := json.Marshal(sc.entries)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(sc.path), 0o700)
+	}
+	if err == nil {
+		tmp := sc.path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0o600); err == nil {
+			err = os.Rename(tmp, sc.path)
+		}
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("path", sc.path).Msg("Failed to write signer cache")
+	}
+}
+
+func sheetCacheKey(url string) string {
+	return "sheet:" + url
+}
+
+func urlCacheKey(url string) string {
+	return "url:" + url
+}
+
+func fileCacheKey(c Config, p, ref string) string {
+	owner, name := c.signersRepo()
+	return "file:" + owner + "/" + name + "/" + p + "@" + ref
+}
+
+// fileSHA looks up the blob SHA of a repo file from its directory listing,
+// which is much cheaper than downloading the file. It returns a 404 error
+// when the file isn't there.
+func fileSHA(ctx context.Context, gh *Client, c Config, p, ref string) (string, error) {
+	dir := path.Dir(p)
+	if dir == "." {
+		dir = ""
+	}
+	owner, name := c.signersRepo()
+	_, entries, _, err := gh.Repositories.GetContents(ctx, owner, name, dir, &github.RepositoryContentGetOptions{Ref: ref})
+	if err != nil {
+		return "", err
+	}
+	for _, e := range entries {
+		if e.GetPath() == p {
+			return e.GetSHA(), nil
+		}
+	}
+	return "", &github.ErrorResponse{
+		Response: &http.Response{StatusCode: http.StatusNotFound},
+		Message:  p + " not found",
+	}
+}
@@ -0,0 +1,62 @@
+package clabot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v58/github"
+	"github.com/rs/zerolog/log"
+)
+
+// defaultCheckboxText is the acknowledgement MODE=checkbox looks for.
+const defaultCheckboxText = "I have read and agree to the CLA"
+
+// checkedBoxRe matches a checked task list item and captures its text.
+var checkedBoxRe = regexp.MustCompile(`(?m)^\s*[-*+]\s+\[[xX]\]\s+(.+?)\s*$`)
+
+// hasCheckedBox reports whether body has a checked task list item containing
+// text, ignoring case, so links or punctuation around it don't matter.
+func hasCheckedBox(body, text string) bool {
+	text = strings.ToLower(text)
+	for _, m := range checkedBoxRe.FindAllStringSubmatch(body, -1) {
+		if strings.Contains(strings.ToLower(m[1]), text) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCheckbox passes the PR when its author checked the CLA acknowledgement
+// in the PR description. Bots are exempt, as in the other modes.
+func checkCheckbox(ctx context.Context, gh *Client, c Config, pr *github.PullRequest) (CheckResult, error) {
+	author := strings.ToLower(pr.GetUser().GetLogin())
+	if isBot(c, author) {
+		res := CheckResult{State: ResultSigned, Description: "Bot author, CLA not required ✔️", Author: author, Exempt: true}
+		postStatus(ctx, gh, c, pr, "success", res.Description, "")
+		return res, nil
+	}
+
+	if hasCheckedBox(pr.GetBody(), c.CheckboxText) {
+		metricsFrom(ctx).recordCheck(pr.GetNumber(), 1, 0, 0)
+		res := CheckResult{State: ResultSigned, Description: "CLA acknowledged ✔️", Author: author}
+		postStatus(ctx, gh, c, pr, "success", res.Description, "")
+		resolveComment(ctx, gh, c, pr.GetNumber())
+		return res, nil
+	}
+
+	log.Info().Str("login", author).Msg("CLA acknowledgement not checked")
+	metricsFrom(ctx).recordCheck(pr.GetNumber(), 0, 1, 0)
+	res := CheckResult{State: ResultUnsigned, UnsignedLogins: []string{author}, Author: author}
+	var state string
+	state, res.Description = unsignedStatus(c, "CLA not acknowledged in the PR description ❌")
+	postStatus(ctx, gh, c, pr, state, res.Description, "")
+
+	msg := fmt.Sprintf("%s please confirm the CLA by adding this line to the PR description and checking the box:\n\n```\n- [x] %s\n```", mention(c, pr.GetUser().GetLogin()), c.CheckboxText)
+	if c.SignURL != "" {
+		msg += "\n\nThe CLA is at " + c.SignURL + "."
+	}
+	upsertComment(ctx, gh, c, pr.GetNumber(), pr.GetHead().GetSHA(), msg)
+	return res, nil
+}
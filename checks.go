@@ -0,0 +1,70 @@
+package clabot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v58/github"
+)
+
+// postCheckRun reports the result as a check run named after StatusContext,
+// updating the run already on sha when there is one. state uses the statuses
+// vocabulary ("pending", "success", "failure").
+func postCheckRun(ctx context.Context, gh *Client, c Config, sha, state, title, summary string) error {
+	status, conclusion := "completed", github.String(state)
+	switch state {
+	case "pending":
+		status, conclusion = "in_progress", nil
+	case "error":
+		conclusion = github.String("failure") // check runs have no error conclusion
+	}
+	if summary == "" {
+		summary = title
+	}
+	output := &github.CheckRunOutput{
+		Title:   github.String(title),
+		Summary: github.String(summary),
+	}
+
+	runs, _, err := gh.Checks.ListCheckRunsForRef(ctx, c.RepoOwner, c.RepoName, sha, &github.ListCheckRunsOptions{
+		CheckName: github.String(c.StatusContext),
+	})
+	if err != nil {
+		return fmt.Errorf("list check runs: %w", err)
+	}
+	if len(runs.CheckRuns) > 0 {
+		_, _, err = gh.Checks.UpdateCheckRun(ctx, c.RepoOwner, c.RepoName, runs.CheckRuns[0].GetID(), github.UpdateCheckRunOptions{
+			Name:       c.StatusContext,
+			Status:     github.String(status),
+			Conclusion: conclusion,
+			Output:     output,
+		})
+		return err
+	}
+	_, _, err = gh.Checks.CreateCheckRun(ctx, c.RepoOwner, c.RepoName, github.CreateCheckRunOptions{
+		Name:       c.StatusContext,
+		HeadSHA:    sha,
+		Status:     github.String(status),
+		Conclusion: conclusion,
+		Output:     output,
+	})
+	return err
+}
+
+// contributorRow is one line of the check run summary.
+type contributorRow struct {
+	Name  string
+	State string
+}
+
+// contributorSummary renders rows as a markdown table for check runs and
+// status replies.
+func contributorSummary(rows []contributorRow) string {
+	var b strings.Builder
+	b.WriteString("| Contributor | CLA |\n|---|---|\n")
+	for _, r := range rows {
+		fmt.Fprintf(&b, "| %s | %s |\n", r.Name, r.State)
+	}
+	return b.String()
+}
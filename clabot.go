@@ -0,0 +1,1054 @@
+// Package clabot implements the CLA and DCO checks behind the clabot command,
+// so they can be embedded in other tools: load a Config, build a client with
+// NewClient, and pass parsed GitHub events to Dispatch.
+package clabot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/oauth2"
+	"golang.org/x/sync/errgroup"
+)
+
+// NewClient authenticates as a GitHub App installation when app credentials
+// are configured and falls back to the static token otherwise. With
+// FORGE=gitlab it returns a client for the GitLab API instead.
+func NewClient(c Config) (*Client, error) {
+	if c.Forge == forgeGitLab {
+		return newGitLabClient(c), nil
+	}
+	var ts oauth2.TokenSource = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: c.Token})
+	if c.usesApp() {
+		app, err := newAppTokenSource(c, c.AppID, c.AppInstallationID, c.AppPrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("github app: %w", err)
+		}
+		log.Info().Int64("app", c.AppID).Int64("installation", c.AppInstallationID).Msg("Authenticating as GitHub App")
+		ts = oauth2.ReuseTokenSource(nil, app)
+	}
+
+	hc := &http.Client{Transport: &retryTransport{
+		base:       newETagTransport(&oauth2.Transport{Source: ts}, c),
+		maxRetries: c.MaxRetries,
+	}}
+	gh, err := withEndpoint(github.NewClient(hc), c)
+	if err != nil {
+		return nil, err
+	}
+	return FromGitHub(gh), nil
+}
+
+const publicAPIURL = "https://api.github.com"
+
+// withEndpoint points the client at a GitHub Enterprise Server instance when
+// APIURL isn't the public endpoint.
+func withEndpoint(gh *github.Client, c Config) (*github.Client, error) {
+	base := strings.TrimSuffix(c.APIURL, "/")
+	if base == "" || base == publicAPIURL {
+		return gh, nil
+	}
+
+	upload := c.UploadURL
+	if upload == "" {
+		// GITHUB_API_URL on GHES is "https://host/api/v3"; uploads live under
+		// "https://host/api/uploads", which go-github derives from the host root.
+		upload = strings.TrimSuffix(base, "/api/v3")
+	}
+
+	ent, err := gh.WithEnterpriseURLs(base, upload)
+	if err != nil {
+		return nil, fmt.Errorf("enterprise urls: %w", err)
+	}
+	log.Info().Str("api", ent.BaseURL.String()).Str("upload", ent.UploadURL.String()).Msg("Using GitHub Enterprise endpoint")
+	return ent, nil
+}
+
+// postStatus reports the check result on the PR's head commit, as a commit
+// status or, with USE_CHECKS_API, a check run, and reconciles the result
+// labels when LABEL_MODE is set. summary is markdown only check runs can show.
+// With STATUS_CONTEXTS it posts the same result under every context.
+func postStatus(ctx context.Context, gh *Client, c Config, pr *github.PullRequest, state, description, summary string) {
+	if len(c.StatusContexts) > 0 && c.Mode == modeCLA {
+		for _, sc := range c.StatusContexts {
+			cc := c
+			cc.StatusContext, cc.StatusContexts = sc.Context, nil
+			postStatus(ctx, gh, cc, pr, state, description, summary)
+		}
+		return
+	}
+	sha := pr.GetHead().GetSHA()
+	log.Info().
+		Str("sha", sha).
+		Str("context", c.StatusContext).
+		Str("state", state).
+		Str("description", description).
+		Bool("dry_run", c.DryRun).
+		Msg("Posting status")
+	posted := "dry run"
+	if c.LabelMode == labelOnly && !c.DryRun {
+		posted = "no (labels only)"
+	}
+	if !c.DryRun && state != "pending" && state != "error" {
+		// A report-only pass is still unsigned as far as labels go.
+		syncLabels(ctx, gh, c, pr.GetNumber(), state == "success" && !strings.HasPrefix(description, reportOnlyPrefix))
+	}
+	if !c.DryRun && c.LabelMode != labelOnly {
+		posted = postStatusOn(ctx, gh, c, sha, state, description, summary)
+		// Branch protection may evaluate the test merge commit instead.
+		if merge := pr.GetMergeCommitSHA(); c.StatusOnMergeSHA && merge != "" && merge != sha {
+			log.Info().Str("sha", merge).Msg("Posting status on merge commit")
+			postStatusOn(ctx, gh, c, merge, state, description, summary)
+		}
+	}
+	if state != "pending" {
+		appendStepSummary(c.StepSummaryPath, c.StatusContext, state, description, posted, summary)
+	}
+}
+
+// postStatusOn posts the status or check run on one commit and says how it
+// went, for the step summary.
+func postStatusOn(ctx context.Context, gh *Client, c Config, sha, state, description, summary string) string {
+	var err error
+	posted := "yes"
+	switch {
+	case c.UseChecksAPI:
+		err = postCheckRun(ctx, gh, c, sha, state, description, summary)
+	case !c.ForceStatus && statusCurrent(ctx, gh, c, sha, state, description):
+		posted = "unchanged"
+	default:
+		err = newForge(gh, c).setStatus(ctx, sha, state, description)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("sha", sha).Msg("Failed to post status")
+		posted = "failed"
+	}
+	return posted
+}
+
+// checkingDescription marks the interim status posted while a check runs.
+const checkingDescription = "Checking CLA…"
+
+// reportOnlyPrefix starts the passing description REPORT_ONLY posts for an
+// unsigned PR.
+const reportOnlyPrefix = "Report only: "
+
+// unsignedStatus returns the status state and description to post for an
+// unsigned PR in any mode: a failure, or under REPORT_ONLY a pass that says
+// what would have failed. The result itself stays unsigned.
+func unsignedStatus(c Config, desc string) (state, description string) {
+	if !c.ReportOnly {
+		return "failure", truncate(desc, maxStatusDescription)
+	}
+	log.Warn().Str("description", desc).Msg("REPORT_ONLY: posting success for an unsigned PR")
+	return "success", truncate(reportOnlyPrefix+strings.TrimSuffix(desc, " ❌")+" ⚠️", maxStatusDescription)
+}
+
+// draftDescription is the pending status SKIP_DRAFTS leaves on drafts.
+const draftDescription = "CLA check deferred until ready for review"
+
+// statusCurrent reports whether posting state would be a no-op: our latest
+// status on sha already says the same, or the interim status is about to
+// cover a result the commit already has. Lookup failures post anyway.
+func statusCurrent(ctx context.Context, gh *Client, c Config, sha, state, description string) bool {
+	statuses, _, err := gh.Repositories.ListStatuses(ctx, c.RepoOwner, c.RepoName, sha, &github.ListOptions{PerPage: 100})
+	if err != nil {
+		log.Warn().Err(err).Str("sha", sha).Msg("Failed to read current statuses")
+		return false
+	}
+	// Newest first, so the first one with our context is the current one.
+	for _, s := range statuses {
+		if s.GetContext() != c.StatusContext {
+			continue
+		}
+		interim := description == checkingDescription && s.GetState() != "pending"
+		if interim || s.GetState() == state && s.GetDescription() == description {
+			log.Info().Str("sha", sha).Str("state", s.GetState()).Msg("Status already current, not posting")
+			return true
+		}
+		return false
+	}
+	return false
+}
+
+func postComment(ctx context.Context, gh *Client, c Config, prNumber int, body string) {
+	if c.DryRun {
+		log.Info().Int("pr", prNumber).Str("body", body).Msg("Dry run: would post comment")
+		return
+	}
+	if c.noComments {
+		return
+	}
+	_ = newForge(gh, c).createComment(ctx, prNumber, body)
+}
+
+func editComment(ctx context.Context, gh *Client, c Config, prNumber int, id int64, body string) {
+	if c.DryRun {
+		log.Info().Int64("comment", id).Str("body", body).Msg("Dry run: would edit comment")
+		return
+	}
+	if c.noComments {
+		return
+	}
+	_ = newForge(gh, c).editComment(ctx, prNumber, id, body)
+}
+
+func deleteComment(ctx context.Context, gh *Client, c Config, prNumber int, id int64) {
+	if c.DryRun {
+		log.Info().Int64("comment", id).Msg("Dry run: would delete comment")
+		return
+	}
+	if c.noComments {
+		return
+	}
+	_ = newForge(gh, c).deleteComment(ctx, prNumber, id)
+}
+
+// commentMarker tags comments written by clabot so later runs can find them.
+const commentMarker = "<!-- clabot -->"
+
+// actionsBot is the login the Actions GITHUB_TOKEN acts as.
+const actionsBot = "github-actions[bot]"
+
+// findBotComment returns the most recent comment on the PR carrying
+// commentMarker that clabot wrote itself, or nil if there is none. Anyone can
+// paste the marker, so the comment's author must be tokenLogin too.
+func findBotComment(ctx context.Context, gh *Client, c Config, prNumber int) (*github.IssueComment, error) {
+	comments, err := listComments(ctx, gh, c, prNumber)
+	if err != nil {
+		return nil, err
+	}
+	bot := gh.tokenLogin(ctx, c)
+	var found *github.IssueComment
+	for _, cm := range comments {
+		if strings.Contains(cm.GetBody(), commentMarker) && strings.EqualFold(cm.GetUser().GetLogin(), bot) {
+			found = cm
+		}
+	}
+	return found, nil
+}
+
+// tokenLogin returns the login clabot comments as, looked up once per
+// client: "<app-slug>[bot]" for App auth, else the token's user.
+// Installation tokens can't read their user, so a token that can't is taken
+// to be the Actions GITHUB_TOKEN.
+func (gh *Client) tokenLogin(ctx context.Context, c Config) string {
+	gh.loginOnce.Do(func() {
+		if c.usesApp() {
+			inst, err := installation(ctx, c)
+			if err != nil {
+				log.Warn().Err(err).Msg("Can't look up the App's login, bot comments won't be found")
+				return
+			}
+			gh.login = inst.GetAppSlug() + "[bot]"
+			return
+		}
+		gh.login = actionsBot
+		if u, _, err := gh.Users.Get(ctx, ""); err == nil {
+			gh.login = u.GetLogin()
+		}
+	})
+	return gh.login
+}
+
+// listComments returns every comment on the PR, oldest first, following
+// pagination; busy PRs easily exceed a single page.
+func listComments(ctx context.Context, gh *Client, c Config, prNumber int) ([]*github.IssueComment, error) {
+	opts := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	return paginate(ctx, &opts.ListOptions, func(*github.ListOptions) ([]*github.IssueComment, *github.Response, error) {
+		return gh.Issues.ListComments(ctx, c.RepoOwner, c.RepoName, prNumber, opts)
+	})
+}
+
+// Values for RESOLVE_COMMENT_MODE.
+const (
+	resolveKeep   = "keep"
+	resolveEdit   = "edit"
+	resolveDelete = "delete"
+)
+
+const resolvedMsg = "Thanks for signing the CLA! ✔️"
+
+// outdatedMsg is appended to the comment for contributors whose signature
+// predates REQUIRED_CLA_VERSION.
+const outdatedMsg = "CLA version %s is now in effect and replaces the version signed by %s, so please sign again."
+
+// satisfiedMsg is the comment COMMENT_ON_SUCCESS keeps on passing PRs.
+const satisfiedMsg = "CLA satisfied ✔️ Everyone on this PR has signed or doesn't need to."
+
+// resolveComment cleans up clabot's failure comment after the CLA has been
+// signed. It is a no-op when there is no prior comment, unless
+// COMMENT_ON_SUCCESS asks for a comment either way.
+func resolveComment(ctx context.Context, gh *Client, c Config, prNumber int) {
+	if c.CommentOnSuccess {
+		commentSuccess(ctx, gh, c, prNumber)
+		return
+	}
+	if c.ResolveMode == resolveKeep {
+		return
+	}
+
+	existing, err := findBotComment(ctx, gh, c, prNumber)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to list comments")
+		return
+	}
+	if existing == nil {
+		return
+	}
+
+	log.Info().Int64("comment", existing.GetID()).Str("mode", c.ResolveMode).Msg("Resolving existing comment")
+	switch c.ResolveMode {
+	case resolveDelete:
+		deleteComment(ctx, gh, c, prNumber, existing.GetID())
+	case resolveEdit:
+		body := commentMarker + "\n" + resolvedMsg
+		if existing.GetBody() == body {
+			return
+		}
+		editComment(ctx, gh, c, prNumber, existing.GetID(), body)
+	}
+}
+
+// commentSuccess turns clabot's comment into satisfiedMsg, posting it if the
+// PR has none, so exactly one comment always shows the current state.
+// Unlike failure comments it ignores the cooldown: the good news is news.
+func commentSuccess(ctx context.Context, gh *Client, c Config, prNumber int) {
+	body := commentMarker + "\n" + satisfiedMsg
+	existing, err := findBotComment(ctx, gh, c, prNumber)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to list comments")
+		return
+	}
+	switch {
+	case existing == nil:
+		postComment(ctx, gh, c, prNumber, body)
+	case existing.GetBody() != body:
+		log.Info().Int64("comment", existing.GetID()).Msg("Updating comment to success")
+		editComment(ctx, gh, c, prNumber, existing.GetID(), body)
+	}
+}
+
+// shaMarker records the head SHA a comment was written for.
+func shaMarker(sha string) string {
+	return "<!-- clabot-sha: " + sha + " -->"
+}
+
+// upsertComment edits clabot's existing comment on the PR, or creates one if
+// none exists, so rechecks don't pile up duplicate comments. With a comment
+// cooldown, an existing comment is left alone while it is younger than the
+// cooldown or was written for the same head SHA.
+func upsertComment(ctx context.Context, gh *Client, c Config, prNumber int, sha, body string) {
+	body = commentMarker + "\n" + shaMarker(sha) + "\n" + body
+
+	existing, err := findBotComment(ctx, gh, c, prNumber)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to list comments, posting a new one")
+	}
+	if existing == nil {
+		postComment(ctx, gh, c, prNumber, body)
+		return
+	}
+
+	if c.CommentCooldown > 0 {
+		last := existing.GetUpdatedAt().Time
+		if last.IsZero() {
+			last = existing.GetCreatedAt().Time
+		}
+		switch {
+		case time.Since(last) < c.CommentCooldown:
+			log.Info().Int64("comment", existing.GetID()).Time("updated", last).Msg("Comment cooldown active, not updating")
+			return
+		case strings.Contains(existing.GetBody(), shaMarker(sha)):
+			log.Info().Int64("comment", existing.GetID()).Str("sha", sha).Msg("Already commented on this head, not updating")
+			return
+		}
+	}
+
+	log.Info().Int64("comment", existing.GetID()).Msg("Updating existing comment")
+	editComment(ctx, gh, c, prNumber, existing.GetID(), body)
+}
+
+// Result is the outcome of a CLA check.
+type Result int
+
+const (
+	ResultNone     Result = iota // no check was run
+	ResultSigned                 // everyone has signed
+	ResultUnsigned               // someone still needs to sign
+)
+
+func (r Result) String() string {
+	switch r {
+	case ResultSigned:
+		return "signed"
+	case ResultUnsigned:
+		return "unsigned"
+	}
+	return "none"
+}
+
+// CheckResult is what a handler decided about a PR, so callers can report on
+// it without re-deriving the outcome.
+type CheckResult struct {
+	State          Result            // ResultNone when no check was run
+	Description    string            // status description posted with the result
+	UnsignedLogins []string          // contributors who still need to sign
+	Author         string            // PR author
+	Provenance     map[string]string // why each passing contributor passes, e.g. "signed via corporate domain example.com"
+	Exempt         bool              // passed without anyone needing to sign, e.g. bots only or docs-only changes
+}
+
+// evaluation is where each contributor on a PR stands.
+type evaluation struct {
+	rows          []contributorRow
+	unsigned      []string // names of contributors who still need to sign
+	outdated      []string // the unsigned who signed an older CLA version
+	signed        int
+	bots, members int
+	paths         int // not required to sign because no rule covers the changed paths
+	listed        int // exempted by a "!login" signers entry
+	domains       int // exempt because all their emails are under EXEMPT_DOMAINS
+
+	provenance map[string]string // why each signed or exempt contributor passes
+}
+
+// note records a contributor who passes and why.
+func (e *evaluation) note(name, state, why string) {
+	e.rows = append(e.rows, contributorRow{name, state})
+	if e.provenance == nil {
+		e.provenance = make(map[string]string)
+	}
+	e.provenance[name] = why
+}
+
+// exempted is the number of contributors who don't need to sign.
+func (e evaluation) exempted() int { return e.bots + e.members + e.paths + e.listed + e.domains }
+
+// clone returns a copy of e that can be added to without changing e.
+func (e evaluation) clone() evaluation {
+	e.rows = slices.Clone(e.rows)
+	e.unsigned = slices.Clone(e.unsigned)
+	e.outdated = slices.Clone(e.outdated)
+	e.provenance = maps.Clone(e.provenance)
+	return e
+}
+
+// exemptions is the part of an evaluation that doesn't depend on the
+// signers: the contributors found exempt, and those left to match.
+type exemptions struct {
+	exempt  evaluation
+	pending []*Contributor
+}
+
+// resolveExemptions collects the PR author and commit authors and settles
+// which are exempt as bots, org members or by email domain. It runs once per
+// check: every signer set, and every STATUS_CONTEXTS entry, is matched
+// against the same result.
+func resolveExemptions(ctx context.Context, gh *Client, c Config, pr *github.PullRequest, commits []*github.RepositoryCommit) (exemptions, error) {
+	var x exemptions
+	e := &x.exempt
+	author := strings.ToLower(pr.GetUser().GetLogin())
+	if !c.IncludeMergeCommits {
+		commits = withoutMerges(commits)
+	}
+	contributors := CollectContributors(author, commits, c.CheckScope, c.CheckIdentity)
+	if c.ResolveEmails {
+		contributors = resolveLogins(ctx, newEmailResolver(gh), c.LookupConcurrency, contributors)
+	}
+
+	// Membership lookups are one API call per contributor; run them side by
+	// side and read the results in contributor order.
+	exempt := newOrgExemptions(gh, c)
+	members := make([]bool, len(contributors))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(c.LookupConcurrency)
+	for i, ct := range contributors {
+		if isBot(c, ct.Login) {
+			continue
+		}
+		g.Go(func() error {
+			ok, err := exempt.isExempt(gctx, ct.Login)
+			members[i] = ok
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return x, fmt.Errorf("membership: %w", err)
+	}
+
+	for i, ct := range contributors {
+		if isBot(c, ct.Login) {
+			log.Info().Str("login", ct.Login).Msg("Exempt from CLA as bot")
+			e.note(ct.name(), "Exempt (bot)", "exempt as a bot")
+			e.bots++
+			continue
+		}
+		if members[i] {
+			log.Info().Str("login", ct.Login).Msg("Exempt from CLA as org member")
+			e.note(ct.name(), "Exempt (org member)", "exempt as an org member")
+			e.members++
+			continue
+		}
+		if exemptDomain(c, ct) {
+			log.Info().Str("contributor", ct.name()).Strs("emails", ct.Emails).Msg("Exempt from CLA by email domain")
+			e.note(ct.name(), "Exempt (domain)", "exempt domain")
+			e.domains++
+			continue
+		}
+		x.pending = append(x.pending, ct)
+	}
+	return x, nil
+}
+
+// matchSigners classifies the contributors x left pending as signed or
+// unsigned against c's signers. The signer lookup is skipped entirely when
+// nobody needs to sign.
+func matchSigners(ctx context.Context, gh *Client, c Config, pr *github.PullRequest, x exemptions) (evaluation, error) {
+	e := x.exempt.clone()
+	pending := x.pending
+	if len(pending) == 0 {
+		return e, nil
+	}
+
+	start := time.Now()
+	sets, err := requiredSigners(ctx, gh, c, pr)
+	promSignerLookup.Observe(time.Since(start).Seconds())
+	if err != nil {
+		return e, err
+	}
+	if len(sets) == 0 {
+		for _, ct := range pending {
+			e.note(ct.name(), "Exempt (paths)", "exempt for the changed paths")
+			e.paths++
+		}
+		return e, nil
+	}
+	for _, ct := range pending {
+		if listedExempt(sets, ct.Login) {
+			log.Info().Str("login", ct.Login).Msg("Exempt from CLA by signers entry")
+			e.note(ct.name(), "Exempt (signers list)", "exempted by the signers list")
+			e.listed++
+			continue
+		}
+		// With path rules every set must match; the first explains why.
+		signed, via := true, ""
+		for i, s := range sets {
+			reason, ok := s.match(ct, c.EmailMatch, c.EmailFoldCase)
+			signed = signed && ok
+			if i == 0 {
+				via = reason
+			}
+		}
+		switch {
+		case !signed && slices.ContainsFunc(sets, func(s SignerSet) bool { return s.outdated(ct, c.EmailMatch, c.EmailFoldCase) }):
+			e.unsigned = append(e.unsigned, ct.name())
+			e.outdated = append(e.outdated, ct.name())
+			e.rows = append(e.rows, contributorRow{ct.name(), "Signed an older CLA, " + c.RequiredCLAVersion + " required ❌"})
+		case !signed:
+			e.unsigned = append(e.unsigned, ct.name())
+			e.rows = append(e.rows, contributorRow{ct.name(), "Not signed ❌"})
+		default:
+			e.signed++
+			e.note(ct.name(), "Signed via "+via+" ✔️", "signed via "+via)
+		}
+	}
+	return e, nil
+}
+
+// listedExempt reports whether any of sets exempts login with "!login".
+func listedExempt(sets []SignerSet, login string) bool {
+	for _, s := range sets {
+		if _, ok := s.Exempt[login]; ok && login != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// HandlePullRequest checks the PR, reports the result on its head commit and
+// records it in the audit log.
+func HandlePullRequest(ctx context.Context, gh *Client, c Config, pr *github.PullRequest) (CheckResult, error) {
+	res, err := checkPullRequest(ctx, gh, c, pr)
+	auditCheck(ctx, c, pr, res, err)
+	return res, err
+}
+
+// checkPullRequest does the work of HandlePullRequest. A check that fails
+// once the pending status is up resolves it to "error".
+func checkPullRequest(ctx context.Context, gh *Client, c Config, pr *github.PullRequest) (_ CheckResult, err error) {
+	author := strings.ToLower(pr.GetUser().GetLogin())
+
+	// A draft stays pending, without a comment, until ready_for_review
+	// brings it back here.
+	if c.SkipDrafts && pr.GetDraft() {
+		log.Info().Int("pr", pr.GetNumber()).Msg("Draft PR, deferring CLA check")
+		res := CheckResult{Description: draftDescription, Author: author}
+		postStatus(ctx, gh, c, pr, "pending", res.Description, "")
+		return res, nil
+	}
+
+	// Show the check as running right away; loading signers can be slow.
+	if !c.DryRun {
+		postStatus(ctx, gh, c, pr, "pending", checkingDescription, "")
+	}
+	defer func() {
+		if err != nil {
+			postErrorStatus(ctx, gh, c, pr, err)
+		}
+	}()
+
+	if c.Mode == modeCheckbox {
+		return checkCheckbox(ctx, gh, c, pr)
+	}
+
+	commits, err := allCommits(ctx, gh, c, pr.GetNumber())
+	if err != nil {
+		return CheckResult{}, fmt.Errorf("list commits: %w", err)
+	}
+
+	if c.Mode == modeDCO {
+		return checkDCO(ctx, gh, c, pr, commits)
+	}
+
+	if len(c.SkipPaths) > 0 {
+		skip, err := onlySkippedPaths(ctx, gh, c, pr.GetNumber())
+		if err != nil {
+			return CheckResult{}, fmt.Errorf("list files: %w", err)
+		}
+		if skip {
+			log.Info().Int("pr", pr.GetNumber()).Msg("Only skipped paths changed, CLA not required")
+			res := CheckResult{State: ResultSigned, Description: skipDescription, Author: author, Exempt: true}
+			postStatus(ctx, gh, c, pr, "success", res.Description, "")
+			resolveComment(ctx, gh, c, pr.GetNumber())
+			return res, nil
+		}
+	}
+
+	if c.ExemptWriteAccess {
+		ok, err := isInternalMaintainerPR(ctx, gh, c, pr)
+		if err != nil {
+			return CheckResult{}, fmt.Errorf("permission: %w", err)
+		}
+		if ok {
+			log.Info().Str("login", author).Msg("Exempt from CLA as maintainer on an internal PR")
+			metricsFrom(ctx).recordCheck(pr.GetNumber(), 0, 0, 1)
+			res := CheckResult{State: ResultSigned, Description: "Internal PR by a maintainer, CLA not required ✔️", Author: author, Exempt: true}
+			postStatus(ctx, gh, c, pr, "success", res.Description, "")
+			resolveComment(ctx, gh, c, pr.GetNumber())
+			return res, nil
+		}
+	}
+
+	x, err := resolveExemptions(ctx, gh, c, pr, commits)
+	if err != nil {
+		return CheckResult{}, err
+	}
+	if len(c.StatusContexts) > 0 {
+		return checkContexts(ctx, gh, c, pr, x)
+	}
+
+	e, err := evaluateSigners(ctx, gh, c, pr, x)
+	if err != nil {
+		return CheckResult{}, err
+	}
+	metricsFrom(ctx).recordCheck(pr.GetNumber(), e.signed, len(e.unsigned), e.exempted())
+	res, err := reportEvaluation(ctx, gh, c, pr, e)
+	if err == nil && res.State == ResultUnsigned {
+		notifySlack(ctx, c, pr, res.UnsignedLogins)
+	}
+	return res, err
+}
+
+// evaluateSigners matches the contributors x left pending against c's
+// signers, polling the sheets for a commenter who just signed.
+func evaluateSigners(ctx context.Context, gh *Client, c Config, pr *github.PullRequest, x exemptions) (evaluation, error) {
+	e, err := matchSigners(ctx, gh, c, pr, x)
+	if err != nil {
+		return e, err
+	}
+	// Someone who comments right after signing is usually ahead of the sheet.
+	if len(e.unsigned) > 0 && c.SheetPoll > 0 && len(c.GoogleSheetUrl) > 0 && actorFrom(ctx) != "" {
+		return pollSheets(ctx, gh, c, pr, x, e)
+	}
+	return e, nil
+}
+
+// reportEvaluation posts the status and comment for e and returns the result.
+func reportEvaluation(ctx context.Context, gh *Client, c Config, pr *github.PullRequest, e evaluation) (CheckResult, error) {
+	author := strings.ToLower(pr.GetUser().GetLogin())
+	sha := pr.GetHead().GetSHA()
+	var err error
+
+	data := messageData{
+		Author:          author,
+		PRNumber:        pr.GetNumber(),
+		UnsignedLogins:  e.unsigned,
+		OutdatedLogins:  e.outdated,
+		RequiredVersion: c.RequiredCLAVersion,
+		SignersURL:      c.SignURL,
+		Signed:          e.signed,
+		Exempt:          e.exempted(),
+	}
+
+	if e.signed == 0 && len(e.unsigned) == 0 {
+		desc := "CLA not required ✔️"
+		switch {
+		case e.bots+e.members+e.listed+e.domains == 0:
+			desc = "CLA not required for the changed paths ✔️"
+		case e.members+e.paths+e.listed+e.domains == 0:
+			desc = "Bot author, CLA not required ✔️"
+		case e.bots+e.paths+e.listed+e.domains == 0:
+			desc = "CLA not required for org members ✔️"
+		case e.bots+e.members+e.paths+e.listed == 0:
+			desc = "CLA not required, exempt domain ✔️"
+		}
+		if desc, err = successDescription(c, desc, data); err != nil {
+			return CheckResult{}, err
+		}
+		postStatus(ctx, gh, c, pr, "success", desc, contributorSummary(e.rows))
+		resolveComment(ctx, gh, c, pr.GetNumber())
+		return CheckResult{State: ResultSigned, Description: desc, Author: author, Provenance: e.provenance, Exempt: true}, nil
+	}
+
+	if len(e.unsigned) == 0 {
+		desc := "CLA signed ✔️"
+		if len(e.provenance) == 1 {
+			// A single contributor's match fits in the description.
+			for _, why := range e.provenance {
+				desc = truncate("CLA "+why+" ✔️", maxStatusDescription)
+			}
+		}
+		if desc, err = successDescription(c, desc, data); err != nil {
+			return CheckResult{}, err
+		}
+		res := CheckResult{State: ResultSigned, Description: desc, Author: author, Provenance: e.provenance}
+		postStatus(ctx, gh, c, pr, "success", res.Description, contributorSummary(e.rows))
+		resolveComment(ctx, gh, c, pr.GetNumber())
+		return res, nil
+	}
+
+	desc, err := render(c.failureDescTpl, data)
+	if err != nil {
+		return CheckResult{}, fmt.Errorf("status description: %w", err)
+	}
+	msg, err := render(c.commentTpl, data)
+	if err != nil {
+		return CheckResult{}, fmt.Errorf("comment: %w", err)
+	}
+	if len(e.outdated) > 0 {
+		msg += "\n\n" + fmt.Sprintf(outdatedMsg, c.RequiredCLAVersion, strings.Join(e.outdated, ", "))
+	}
+
+	// Within the grace period the check waits for the signature rather than
+	// failing; it turns red on the first check after the period ends.
+	if left := c.GracePeriod - time.Since(pr.GetCreatedAt().Time); c.GracePeriod > 0 && left > 0 {
+		res := CheckResult{Description: truncate("CLA not signed yet, "+formatRemaining(left)+" left to sign ⏳", maxStatusDescription), UnsignedLogins: e.unsigned, Author: author}
+		log.Info().Dur("left", left).Strs("unsigned", e.unsigned).Msg("Unsigned within grace period")
+		postStatus(ctx, gh, c, pr, "pending", res.Description, contributorSummary(e.rows))
+		upsertComment(ctx, gh, c, pr.GetNumber(), sha, msg)
+		return res, nil
+	}
+
+	res := CheckResult{State: ResultUnsigned, UnsignedLogins: e.unsigned, Author: author, Provenance: e.provenance}
+	var state string
+	state, res.Description = unsignedStatus(c, desc)
+	postStatus(ctx, gh, c, pr, state, res.Description, contributorSummary(e.rows))
+	upsertComment(ctx, gh, c, pr.GetNumber(), sha, msg)
+	return res, nil
+}
+
+// successDescription renders STATUS_SUCCESS_DESC over data, or returns def
+// when it isn't set.
+func successDescription(c Config, def string, data messageData) (string, error) {
+	if c.successDescTpl == nil {
+		return def, nil
+	}
+	desc, err := render(c.successDescTpl, data)
+	if err != nil {
+		return "", fmt.Errorf("status description: %w", err)
+	}
+	return truncate(desc, maxStatusDescription), nil
+}
+
+// allCommits returns every commit on the PR.
+func allCommits(ctx context.Context, gh *Client, c Config, prNumber int) ([]*github.RepositoryCommit, error) {
+	return paginate(ctx, &github.ListOptions{PerPage: 100}, func(opts *github.ListOptions) ([]*github.RepositoryCommit, *github.Response, error) {
+		return gh.PullRequests.ListCommits(ctx, c.RepoOwner, c.RepoName, prNumber, opts)
+	})
+}
+
+// withoutMerges drops merge commits. Their committer is whoever pressed the
+// merge button (or web-flow), not a contributor of the change.
+func withoutMerges(commits []*github.RepositoryCommit) []*github.RepositoryCommit {
+	var out []*github.RepositoryCommit
+	for _, rc := range commits {
+		if len(rc.Parents) <= 1 {
+			out = append(out, rc)
+		}
+	}
+	return out
+}
+
+// formatRemaining renders a grace period countdown like "2d 5h" or "40m".
+func formatRemaining(d time.Duration) string {
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", max(int(d.Minutes()), 1))
+	}
+	days, hours := int(d.Hours())/24, int(d.Hours())%24
+	if days == 0 {
+		return fmt.Sprintf("%dh", hours)
+	}
+	return fmt.Sprintf("%dd %dh", days, hours)
+}
+
+// errorStatusTimeout bounds posting the error status, which may happen after
+// the run's own deadline has passed.
+const errorStatusTimeout = 10 * time.Second
+
+// postErrorStatus replaces the pending status of a check that failed with
+// err, so the PR isn't left waiting on a check that will never finish.
+func postErrorStatus(ctx context.Context, gh *Client, c Config, pr *github.PullRequest, err error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), errorStatusTimeout)
+	defer cancel()
+	desc := "CLA check failed: " + err.Error()
+	if misconfigured(err) {
+		// Rerunning won't help; a maintainer has to step in.
+		desc = "CLA signer source misconfigured: " + err.Error()
+	}
+	postStatus(ctx, gh, c, pr, "error", truncate(desc, maxStatusDescription), "")
+}
+
+// GitHub rejects commit status descriptions longer than this.
+const maxStatusDescription = 140
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// HandleIssueComment runs the command in a PR comment, if there is one.
+func HandleIssueComment(ctx context.Context, gh *Client, c Config, ev *github.IssueCommentEvent) (CheckResult, error) {
+	// Ignore comments written by the bot itself
+	author := strings.ToLower(ev.GetComment().GetUser().GetLogin())
+	if _, skip := c.IgnoreAuthors[author]; skip {
+		return CheckResult{}, nil
+	}
+
+	// We only care if the comment is on a PR
+	if ev.GetIssue().IsPullRequest() == false {
+		return CheckResult{}, nil
+	}
+	body := strings.ToLower(ev.GetComment().GetBody())
+
+	cmd, args, ok := parseCommand(body, c.Triggers)
+	if !ok {
+		log.Info().Str("body", body).Msg("Ignoring comment")
+		return CheckResult{}, nil // nothing to do
+	}
+
+	ctx = withActor(ctx, author)
+
+	// The comment event only carries the issue; fetch the PR to act on it.
+	prNum := ev.GetIssue().GetNumber()
+	pr, _, err := gh.PullRequests.Get(ctx, c.RepoOwner, c.RepoName, prNum)
+	if err != nil {
+		return CheckResult{}, err
+	}
+	if pr.GetState() != "open" && !c.CheckClosedPRs {
+		log.Info().Int("pr", prNum).Str("state", pr.GetState()).Bool("merged", pr.GetMerged()).Msg("Ignoring command on closed PR")
+		return CheckResult{}, nil
+	}
+
+	switch cmd {
+	case "override":
+		return handleOverride(ctx, gh, c, pr, author)
+	case "sign":
+		return handleSign(ctx, gh, c, pr, author)
+	case "status":
+		return handleStatus(ctx, gh, c, pr, author)
+	case "revoke":
+		return handleRevoke(ctx, gh, c, pr, author, args)
+	case "refresh":
+		return handleRefresh(ctx, gh, c, pr, author)
+	case "whoami":
+		return handleWhoami(ctx, gh, c, pr, author)
+	default:
+		return HandlePullRequest(ctx, gh, c, pr)
+	}
+}
+
+// handleRerequest re-runs the check for the PRs attached to a check run or
+// suite the user asked GitHub to re-run. The payload's PRs are minimal, so each
+// one is fetched first; the worst result wins.
+func handleRerequest(ctx context.Context, gh *Client, c Config, prs []*github.PullRequest) (CheckResult, error) {
+	var res CheckResult
+	for _, p := range prs {
+		pr, _, err := gh.PullRequests.Get(ctx, c.RepoOwner, c.RepoName, p.GetNumber())
+		if err != nil {
+			return res, err
+		}
+		if pr.GetState() != "open" && !c.CheckClosedPRs {
+			log.Info().Int("pr", pr.GetNumber()).Msg("Ignoring re-run on closed PR")
+			continue
+		}
+		r, err := HandlePullRequest(ctx, gh, c, pr)
+		if err != nil {
+			return res, err
+		}
+		if r.State > res.State {
+			res = r
+		}
+	}
+	return res, nil
+}
+
+// eventRepo returns the repository an event belongs to, or nil for events
+// clabot doesn't handle.
+func eventRepo(event any) *github.Repository {
+	switch ev := event.(type) {
+	case *github.PullRequestEvent:
+		return ev.GetRepo()
+	case *github.PullRequestTargetEvent:
+		return ev.GetRepo()
+	case *github.IssueCommentEvent:
+		return ev.GetRepo()
+	case *github.CheckRunEvent:
+		return ev.GetRepo()
+	case *github.CheckSuiteEvent:
+		return ev.GetRepo()
+	}
+	return nil
+}
+
+// forRepo returns a copy of c targeting repo. The event payload is
+// authoritative, so one config can serve several repositories; GITHUB_REPOSITORY
+// is only the fallback when the payload carries no repository. The repo's
+// entry in repos, if any, is applied on top.
+func forRepo(c Config, repo *github.Repository) Config {
+	if owner, name := repo.GetOwner().GetLogin(), repo.GetName(); owner != "" && name != "" {
+		c.RepoOwner, c.RepoName = owner, name
+	}
+	return c.withRepoOverrides(c.RepoOwner, c.RepoName)
+}
+
+// prActionEnabled reports whether PR_ACTIONS lets a pull_request event with
+// action through. A synchronize carries the new head SHA, which the check then
+// reports on like any other.
+func prActionEnabled(c Config, action string) bool {
+	if len(c.PRActions) == 0 || slices.Contains(c.PRActions, action) {
+		return true
+	}
+	if c.SkipDrafts && action == "ready_for_review" {
+		return true // the deferred check has to run some time
+	}
+	log.Info().Str("action", action).Strs("pr_actions", c.PRActions).Msg("Ignoring pull request action")
+	return false
+}
+
+// Dispatch runs the handler for a parsed event; unsupported events, including
+// a nil one, are ignored.
+func Dispatch(ctx context.Context, gh *Client, c Config, event any) (CheckResult, error) {
+	c = forRepo(c, eventRepo(event))
+	switch ev := event.(type) {
+	case *github.PullRequestEvent:
+		if !prActionEnabled(c, ev.GetAction()) {
+			return CheckResult{}, nil
+		}
+		log.Info().Msg("Handling pull request")
+		pr := ev.GetPullRequest()
+		if gh.gitlab != nil {
+			// Merge request hooks name the author by ID only; read the MR.
+			var err error
+			if pr, _, err = gh.PullRequests.Get(ctx, c.RepoOwner, c.RepoName, ev.GetNumber()); err != nil {
+				return CheckResult{}, err
+			}
+		}
+		return HandlePullRequest(ctx, gh, c, pr)
+	case *github.PullRequestTargetEvent:
+		// Same payload as pull_request, but runs with a writable token on
+		// PRs from forks.
+		if !prActionEnabled(c, ev.GetAction()) {
+			return CheckResult{}, nil
+		}
+		log.Info().Msg("Handling pull request target")
+		return HandlePullRequest(ctx, gh, c, ev.GetPullRequest())
+	case *github.IssueCommentEvent:
+		log.Info().Msg("Handling issue comment")
+		return HandleIssueComment(ctx, gh, c, ev)
+	case *github.CheckRunEvent:
+		// Other apps' runs share the event; only re-run ours.
+		if ev.GetAction() != "rerequested" || ev.GetCheckRun().GetName() != c.StatusContext {
+			return CheckResult{}, nil
+		}
+		log.Info().Msg("Handling check run re-run")
+		return handleRerequest(ctx, gh, c, ev.GetCheckRun().PullRequests)
+	case *github.CheckSuiteEvent:
+		if ev.GetAction() != "rerequested" {
+			return CheckResult{}, nil
+		}
+		log.Info().Msg("Handling check suite re-run")
+		return handleRerequest(ctx, gh, c, ev.GetCheckSuite().PullRequests)
+	default:
+		log.
+			Info().
+			Str("event", c.EventName).
+			Msg("Ignored event")
+		return CheckResult{}, nil
+	}
+}
+
+// ------------------------------------------------------------
+// ParseEvent decodes the Actions event payload at path, or from stdin when
+// path is "-". Event types go-github doesn't know about yield a nil event so
+// Dispatch can ignore them.
+func ParseEvent(name, path string) (any, error) {
+	return ParseForgeEvent(forgeGitHub, name, path)
+}
+
+// ParseForgeEvent is ParseEvent for the code host named by FORGE. GitLab
+// hooks are named as in the X-Gitlab-Event header, e.g. "Merge Request Hook".
+func ParseForgeEvent(forge, name, path string) (any, error) {
+	if !knownEvent(forge, name) {
+		return nil, nil
+	}
+	log.Info().Str("path", path).Msg("parsing event")
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseWebHook(forge, name, data)
+}
+
+func knownEvent(forge, name string) bool {
+	if forge == forgeGitLab {
+		return name == gitlabMergeRequestHook || name == gitlabNoteHook
+	}
+	return github.EventForType(name) != nil
+}
+
+// parseWebHook decodes a payload of the named event type as sent by forge.
+func parseWebHook(forge, name string, payload []byte) (any, error) {
+	if forge == forgeGitLab {
+		return parseGitLabWebHook(name, payload)
+	}
+	return github.ParseWebHook(name, payload)
+}
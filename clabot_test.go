@@ -0,0 +1,219 @@
+package clabot
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v58/github"
+	"github.com/rs/zerolog"
+)
+
+func TestMain(m *testing.M) {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	os.Exit(m.Run())
+}
+
+func testConfig(t *testing.T) Config {
+	t.Helper()
+	t.Setenv("GITHUB_REPOSITORY", "octo-org/octo-repo")
+	t.Setenv("SIGNERS_PATH", ".github/signers.txt")
+	c, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	return c
+}
+
+func testPR(author string) *github.PullRequest {
+	return &github.PullRequest{
+		Number: github.Int(7),
+		State:  github.String("open"),
+		User:   &github.User{Login: github.String(author)},
+		Head:   &github.PullRequestBranch{SHA: github.String("abc123")},
+	}
+}
+
+func TestHandlePullRequest(t *testing.T) {
+	tests := []struct {
+		name         string
+		signers      string
+		commits      []*github.RepositoryCommit
+		skipBots     bool
+		wantState    string
+		wantUnsigned []string
+		wantComment  bool
+	}{
+		{
+			name:      "all signed",
+			signers:   "octocat\nhubot\n",
+			commits:   []*github.RepositoryCommit{commit("octocat", "octocat@example.com"), commit("hubot", "hubot@example.com")},
+			wantState: "success",
+		},
+		{
+			name:         "co-author unsigned",
+			signers:      "octocat\n",
+			commits:      []*github.RepositoryCommit{commit("octocat", "octocat@example.com"), commit("hubot", "hubot@example.com")},
+			wantState:    "failure",
+			wantUnsigned: []string{"hubot"},
+			wantComment:  true,
+		},
+		{
+			name:         "bot commits",
+			signers:      "octocat\n",
+			commits:      []*github.RepositoryCommit{commit("octocat", "octocat@example.com"), commit("dependabot[bot]", "bot@example.com")},
+			wantState:    "failure",
+			wantUnsigned: []string{"dependabot[bot]"},
+			wantComment:  true,
+		},
+		{
+			name:      "bot commits with SKIP_BOTS",
+			signers:   "octocat\n",
+			commits:   []*github.RepositoryCommit{commit("octocat", "octocat@example.com"), commit("dependabot[bot]", "bot@example.com")},
+			skipBots:  true,
+			wantState: "success",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeGitHub()
+			f.files[".github/signers.txt"] = tt.signers
+			f.commits = tt.commits
+
+			c := testConfig(t)
+			c.SkipBots = tt.skipBots
+
+			res, err := HandlePullRequest(context.Background(), f.client(), c, testPR("octocat"))
+			if err != nil {
+				t.Fatalf("HandlePullRequest: %v", err)
+			}
+			if !slices.Equal(res.UnsignedLogins, tt.wantUnsigned) {
+				t.Errorf("unsigned = %q, want %q", res.UnsignedLogins, tt.wantUnsigned)
+			}
+			if len(f.statuses) == 0 {
+				t.Fatal("no status posted")
+			}
+			if got := f.statuses[0]; got.GetState() != tt.wantState || got.GetContext() != "CLA check" {
+				t.Errorf("status = %s %q on %q, want %s on \"CLA check\"", got.GetState(), got.GetDescription(), got.GetContext(), tt.wantState)
+			}
+			if got := f.statuses[len(f.statuses)-1]; got.GetState() != "pending" {
+				t.Errorf("first status = %s, want pending", got.GetState())
+			}
+			if (len(f.comments) > 0) != tt.wantComment {
+				t.Fatalf("%d comments, want comment %v", len(f.comments), tt.wantComment)
+			}
+			for _, login := range tt.wantUnsigned {
+				if !strings.Contains(f.comments[0].GetBody(), "@"+login) {
+					t.Errorf("comment does not mention @%s:\n%s", login, f.comments[0].GetBody())
+				}
+			}
+		})
+	}
+}
+
+func TestHandlePullRequestAfterSigning(t *testing.T) {
+	f := newFakeGitHub()
+	f.files[".github/signers.txt"] = "octocat\n"
+	f.commits = []*github.RepositoryCommit{commit("octocat", "octocat@example.com"), commit("hubot", "hubot@example.com")}
+	c := testConfig(t)
+	c.SignersCacheTTL = 0
+
+	if _, err := HandlePullRequest(context.Background(), f.client(), c, testPR("octocat")); err != nil {
+		t.Fatalf("first check: %v", err)
+	}
+	if len(f.comments) != 1 {
+		t.Fatalf("%d comments after the first check, want 1", len(f.comments))
+	}
+
+	f.files[".github/signers.txt"] = "octocat\nhubot\n"
+	res, err := HandlePullRequest(context.Background(), f.client(), c, testPR("octocat"))
+	if err != nil {
+		t.Fatalf("second check: %v", err)
+	}
+	if res.State != ResultSigned {
+		t.Errorf("state = %v, want signed", res.State)
+	}
+	if got := f.statuses[0].GetState(); got != "success" {
+		t.Errorf("status = %s, want success", got)
+	}
+	if len(f.comments) > 1 {
+		t.Errorf("%d comments, want the first one reused", len(f.comments))
+	}
+}
+
+func TestDispatchPullRequestTarget(t *testing.T) {
+	ev, err := ParseEvent("pull_request_target", "testdata/pull_request_target.json")
+	if err != nil {
+		t.Fatalf("ParseEvent: %v", err)
+	}
+	if _, ok := ev.(*github.PullRequestTargetEvent); !ok {
+		t.Fatalf("ParseEvent returned %T, want *github.PullRequestTargetEvent", ev)
+	}
+
+	f := newFakeGitHub()
+	f.files[".github/signers.txt"] = "octocat\n"
+	f.commits = []*github.RepositoryCommit{commit("octocat", "octocat@example.com")}
+	res, err := Dispatch(context.Background(), f.client(), testConfig(t), ev)
+	if err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	if res.State != ResultSigned {
+		t.Errorf("state = %v, want signed", res.State)
+	}
+	if len(f.statuses) == 0 || f.statuses[0].GetState() != "success" {
+		t.Fatalf("statuses = %v, want success on top", f.statuses)
+	}
+}
+
+func TestDispatchPullRequestTargetIgnoredAction(t *testing.T) {
+	ev, err := ParseEvent("pull_request_target", "testdata/pull_request_target.json")
+	if err != nil {
+		t.Fatalf("ParseEvent: %v", err)
+	}
+	ev.(*github.PullRequestTargetEvent).Action = github.String("labeled")
+
+	f := newFakeGitHub()
+	c := testConfig(t)
+	c.PRActions = stringList{"opened", "synchronize", "reopened"}
+	if _, err := Dispatch(context.Background(), f.client(), c, ev); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	if len(f.statuses) != 0 {
+		t.Errorf("posted %d statuses for a labeled event", len(f.statuses))
+	}
+}
+
+func TestParseEventStdin(t *testing.T) {
+	f, err := os.Open("testdata/pull_request_target.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	stdin := os.Stdin
+	os.Stdin = f
+	t.Cleanup(func() { os.Stdin = stdin })
+
+	ev, err := ParseEvent("pull_request_target", "-")
+	if err != nil {
+		t.Fatalf("ParseEvent: %v", err)
+	}
+	pr, ok := ev.(*github.PullRequestTargetEvent)
+	if !ok {
+		t.Fatalf("ParseEvent returned %T, want *github.PullRequestTargetEvent", ev)
+	}
+	if got := pr.GetPullRequest().GetNumber(); got != 7 {
+		t.Errorf("PR number = %d, want 7", got)
+	}
+	if got := pr.GetRepo().GetFullName(); got != "octo-org/octo-repo" {
+		t.Errorf("repo = %s, want octo-org/octo-repo", got)
+	}
+}
+
+func TestParseEventUnknownName(t *testing.T) {
+	ev, err := ParseEvent("not_an_event", "-")
+	if ev != nil || err != nil {
+		t.Errorf("ParseEvent = %v, %v; want nil, nil without reading stdin", ev, err)
+	}
+}
@@ -0,0 +1,200 @@
+// Command clabot checks that the contributors on a pull request have signed
+// the CLA. It runs as a GitHub Action by default; "clabot serve" handles
+// webhook deliveries, "clabot doctor" validates the configuration and
+// "clabot recheck-open" re-runs the check on every open PR.
+// "clabot version" prints the build version and "clabot validate-config"
+// checks a config file. With --event-stdin the event
+// payload is read from stdin rather than GITHUB_EVENT_PATH.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime/debug"
+	"strings"
+	"syscall"
+
+	"github.com/prequel-dev/clabot"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Build metadata, set with -ldflags "-X main.version=... -X main.commit=...
+// -X main.date=...". version falls back to the module version for builds
+// from go install or go run.
+var (
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
+)
+
+// buildVersion returns version, or the module version when it's unset.
+func buildVersion() string {
+	if version != "dev" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return version
+}
+
+// Process exit codes.
+const (
+	exitUnsigned = 1 // CLA check failed and FAIL_ON_UNSIGNED is set
+	exitError    = 2 // operational error
+)
+
+func main() {
+	os.Exit(run())
+}
+
+// setupLogging applies LOG_LEVEL and LOG_FORMAT. It runs before the config
+// is loaded so that config warnings are formatted too.
+func setupLogging() {
+	if s := os.Getenv("LOG_LEVEL"); s != "" {
+		level, err := zerolog.ParseLevel(strings.ToLower(s))
+		if err != nil {
+			log.Warn().Str("value", s).Msg("Ignoring invalid LOG_LEVEL")
+		} else {
+			zerolog.SetGlobalLevel(level)
+		}
+	}
+	switch format := strings.ToLower(os.Getenv("LOG_FORMAT")); format {
+	case "", "json":
+	case "console":
+		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	default:
+		log.Warn().Str("value", format).Msg("Ignoring unknown LOG_FORMAT")
+	}
+}
+
+// validateConfig loads the config file named by args, or CLABOT_CONFIG, with
+// all of LoadConfig's checks but without contacting GitHub.
+func validateConfig(args []string) int {
+	if len(args) > 0 {
+		os.Setenv("CLABOT_CONFIG", args[0])
+	}
+	path := os.Getenv("CLABOT_CONFIG")
+	if path == "" {
+		log.Error().Msg("validate-config: pass a config file or set CLABOT_CONFIG")
+		return exitError
+	}
+	if _, err := clabot.LoadConfig(); err != nil {
+		log.Error().Err(err).Msg("Invalid config")
+		return exitError
+	}
+	fmt.Printf("%s: OK\n", path)
+	return 0
+}
+
+// run executes clabot and returns the process exit code.
+func run() int {
+	if len(os.Args) > 1 && (os.Args[1] == "version" || os.Args[1] == "--version" || os.Args[1] == "-version") {
+		fmt.Printf("clabot %s (commit %s, built %s)\n", buildVersion(), commit, date)
+		return 0
+	}
+
+	setupLogging()
+	if len(os.Args) > 1 && os.Args[1] == "validate-config" {
+		return validateConfig(os.Args[2:])
+	}
+	log.Info().Str("version", buildVersion()).Str("commit", commit).Str("date", date).Msg("Starting clabot")
+	c, err := clabot.LoadConfig()
+	if err != nil {
+		log.Error().Err(err).Msg("clabot error")
+		return exitError
+	}
+	gh, err := clabot.NewClient(c)
+	if err != nil {
+		log.Error().Err(err).Msg("clabot error")
+		return exitError
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		ctx, cancel := context.WithTimeout(context.Background(), c.RunTimeout)
+		defer cancel()
+		if !clabot.Doctor(ctx, gh, c, os.Stdout) {
+			return exitError
+		}
+		return 0
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "recheck-open" {
+		fs := flag.NewFlagSet("recheck-open", flag.ContinueOnError)
+		noComments := fs.Bool("no-comments", false, "only update statuses and labels")
+		if err := fs.Parse(os.Args[2:]); err != nil {
+			return exitError
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := clabot.RecheckOpen(ctx, gh, c, os.Stdout, !*noComments); err != nil {
+			log.Error().Err(err).Msg("clabot error")
+			warnPermissions(ctx, gh, c)
+			return exitError
+		}
+		return 0
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		// Checked once up front; the server outlives any single run.
+		permCtx, cancel := context.WithTimeout(ctx, c.RunTimeout)
+		warnPermissions(permCtx, gh, c)
+		cancel()
+		if err := clabot.Serve(ctx, c, gh); err != nil {
+			log.Error().Err(err).Msg("clabot error")
+			return exitError
+		}
+		return 0
+	}
+
+	metrics := clabot.NewRunMetrics(c)
+	ctx, cancel := context.WithTimeout(clabot.WithMetrics(context.Background(), metrics), c.RunTimeout)
+	defer cancel()
+
+	eventPath := c.EventPath
+	if len(os.Args) > 1 && os.Args[1] == "--event-stdin" {
+		eventPath = "-"
+	}
+	var res clabot.CheckResult
+	event, err := clabot.ParseForgeEvent(c.Forge, c.EventName, eventPath)
+	if err == nil {
+		res, err = clabot.Dispatch(ctx, gh, c, event)
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("run timed out after %s (RUN_TIMEOUT): %w", c.RunTimeout, ctx.Err())
+	}
+	metrics.Write(c.MetricsPath, res.State, err)
+
+	if err != nil {
+		log.Error().Err(err).Msg("clabot error")
+		warnPermissions(ctx, gh, c)
+		return exitError
+	}
+	if res.State != clabot.ResultNone {
+		log.Info().Str("result", res.State.String()).Str("author", res.Author).Strs("unsigned", res.UnsignedLogins).Msg(res.Description)
+	}
+	if res.State == clabot.ResultUnsigned && c.FailOnUnsigned && !c.ReportOnly {
+		return exitUnsigned
+	}
+	return 0
+}
+
+// warnPermissions logs the permissions the token lacks, a common reason for
+// failed runs. It uses ctx's deadline and is skipped once that has passed.
+func warnPermissions(ctx context.Context, gh *clabot.Client, c clabot.Config) {
+	if ctx.Err() != nil {
+		return
+	}
+	if missing, _, err := clabot.CheckPermissions(ctx, gh, c); err != nil {
+		log.Warn().Err(err).Msg("Could not check token permissions")
+	} else if len(missing) > 0 {
+		log.Warn().Strs("missing", missing).Msg("Token lacks permissions clabot needs; statuses or comments will fail")
+	}
+}
@@ -0,0 +1,388 @@
+package clabot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v58/github"
+	"github.com/rs/zerolog/log"
+)
+
+// defaultTrigger is the mention that starts a command unless BOT_TRIGGER
+// names others.
+const defaultTrigger = "@cla-bot"
+
+// parseCommand extracts the command word and its arguments from a comment
+// such as "@cla-bot check", where the mention is any of triggers. body and
+// triggers are expected in lower case. Unknown commands are not recognized.
+func parseCommand(body string, triggers []string) (string, []string, bool) {
+	var fields []string
+	for _, t := range triggers {
+		rest, ok := strings.CutPrefix(body, t)
+		// The trigger must be a whole word: "@cla-botcheck" is not a command.
+		if ok && rest != "" && strings.TrimLeft(rest, " \t\r\n") != rest {
+			fields = strings.Fields(rest)
+			break
+		}
+	}
+	if len(fields) == 0 {
+		return "", nil, false
+	}
+	switch fields[0] {
+	case "check", "override", "sign", "status", "revoke", "refresh", "whoami":
+		return fields[0], fields[1:], true
+	}
+	return "", nil, false
+}
+
+// handleOverride forces the check green when a maintainer vouches for the
+// contributors, e.g. because their CLA was handled out of band.
+func handleOverride(ctx context.Context, gh *Client, c Config, pr *github.PullRequest, actor string) (CheckResult, error) {
+	ok, err := hasWriteAccess(ctx, gh, c, actor)
+	if err != nil {
+		return CheckResult{}, fmt.Errorf("permission: %w", err)
+	}
+	if !ok {
+		log.Warn().Str("actor", actor).Int("pr", pr.GetNumber()).Msg("Rejected CLA override")
+		msg := fmt.Sprintf("@%s sorry, only maintainers with write access can override the CLA check.", actor)
+		postComment(ctx, gh, c, pr.GetNumber(), msg)
+		return CheckResult{}, nil
+	}
+
+	log.Info().Str("actor", actor).Int("pr", pr.GetNumber()).Str("sha", pr.GetHead().GetSHA()).Msg("CLA overridden")
+	res := CheckResult{State: ResultSigned, Description: truncate("CLA overridden by @"+actor, maxStatusDescription), Author: strings.ToLower(pr.GetUser().GetLogin())}
+	postStatus(ctx, gh, c, pr, "success", res.Description, "")
+	audit(ctx, c, pr, "overridden", res.Description)
+	return res, nil
+}
+
+// hasWriteAccess reports whether login can push to the repository.
+func hasWriteAccess(ctx context.Context, gh *Client, c Config, login string) (bool, error) {
+	perm, _, err := gh.Repositories.GetPermissionLevel(ctx, c.RepoOwner, c.RepoName, login)
+	if err != nil {
+		return false, err
+	}
+	switch perm.GetPermission() {
+	case "admin", "maintain", "write":
+		return true, nil
+	}
+	return false, nil
+}
+
+// handleStatus replies with where each contributor on the PR stands, per
+// status context, leaving the commit status untouched.
+func handleStatus(ctx context.Context, gh *Client, c Config, pr *github.PullRequest, actor string) (CheckResult, error) {
+	if c.Mode != modeCLA {
+		log.Info().Str("actor", actor).Str("mode", c.Mode).Msg("Ignoring status command outside CLA mode")
+		return CheckResult{}, nil
+	}
+	commits, err := allCommits(ctx, gh, c, pr.GetNumber())
+	if err != nil {
+		return CheckResult{}, fmt.Errorf("list commits: %w", err)
+	}
+	x, err := resolveExemptions(ctx, gh, c, pr, commits)
+	if err != nil {
+		return CheckResult{}, err
+	}
+
+	var b strings.Builder
+	if len(c.StatusContexts) > 0 {
+		fmt.Fprintf(&b, "@%s here is where the contributors stand for each CLA:\n", actor)
+	}
+	for _, cc := range contextConfigs(c) {
+		e, err := matchSigners(ctx, gh, cc, pr, x)
+		if err != nil {
+			return CheckResult{}, err
+		}
+		if len(c.StatusContexts) == 0 {
+			fmt.Fprintf(&b, "@%s ", actor)
+		} else {
+			contextHeading(&b, c, cc)
+		}
+		fmt.Fprintf(&b, "%d of %d contributors still need to sign the CLA.\n\n%s",
+			len(e.unsigned), len(e.rows), contributorSummary(e.rows))
+	}
+	postComment(ctx, gh, c, pr.GetNumber(), b.String())
+	return CheckResult{}, nil
+}
+
+// handleSign records the commenter as a signer by committing their login to
+// the signers file, then re-runs the check.
+func handleSign(ctx context.Context, gh *Client, c Config, pr *github.PullRequest, actor string) (CheckResult, error) {
+	if !c.SelfSign {
+		log.Info().Str("actor", actor).Msg("Ignoring sign command, SELF_SIGN is off")
+		return CheckResult{}, nil
+	}
+
+	path, branch, err := signersFileTarget(c, pr)
+	if err != nil {
+		return CheckResult{}, fmt.Errorf("sign: %w", err)
+	}
+	content, sha, err := readSignersFile(ctx, gh, c, path, branch)
+	if err != nil {
+		return CheckResult{}, fmt.Errorf("sign: %w", err)
+	}
+
+	current, err := parseSignersFile(content, c.SignersFormat, repoEntries)
+	if err != nil {
+		return CheckResult{}, fmt.Errorf("sign: parse %s: %w", path, err)
+	}
+	if _, ok := current.Logins[actor]; ok {
+		log.Info().Str("login", actor).Str("path", path).Msg("Already in signers file")
+		return HandlePullRequest(ctx, gh, c, pr)
+	}
+
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	content += actor + "\n"
+
+	var msg strings.Builder
+	if err := c.signCommitTpl.Execute(&msg, struct {
+		Login    string
+		PRNumber int
+	}{actor, pr.GetNumber()}); err != nil {
+		return CheckResult{}, fmt.Errorf("sign: commit message: %w", err)
+	}
+
+	log.Info().Str("login", actor).Str("path", path).Str("branch", branch).Bool("dry_run", c.DryRun).Msg("Adding signer")
+	if err := writeSignersFile(ctx, gh, c, path, branch, content, sha, msg.String()); err != nil {
+		return CheckResult{}, fmt.Errorf("sign: commit: %w", err)
+	}
+
+	return HandlePullRequest(ctx, gh, c, pr)
+}
+
+// handleRevoke removes a login from the signers file on a maintainer's
+// request, then re-runs the check if the login is this PR's author.
+func handleRevoke(ctx context.Context, gh *Client, c Config, pr *github.PullRequest, actor string, args []string) (CheckResult, error) {
+	ok, err := hasWriteAccess(ctx, gh, c, actor)
+	if err != nil {
+		return CheckResult{}, fmt.Errorf("permission: %w", err)
+	}
+	if !ok {
+		log.Warn().Str("actor", actor).Int("pr", pr.GetNumber()).Msg("Rejected CLA revoke")
+		postComment(ctx, gh, c, pr.GetNumber(), fmt.Sprintf("@%s sorry, only maintainers with write access can revoke a CLA signature.", actor))
+		return CheckResult{}, nil
+	}
+	if len(args) == 0 {
+		postComment(ctx, gh, c, pr.GetNumber(), fmt.Sprintf("@%s usage: `%s revoke @login`", actor, c.Triggers[0]))
+		return CheckResult{}, nil
+	}
+	login := strings.TrimPrefix(args[0], "@")
+
+	path, branch, err := signersFileTarget(c, pr)
+	if err != nil {
+		return CheckResult{}, fmt.Errorf("revoke: %w", err)
+	}
+	content, sha, err := readSignersFile(ctx, gh, c, path, branch)
+	if err != nil {
+		return CheckResult{}, fmt.Errorf("revoke: %w", err)
+	}
+
+	var kept []string
+	removed := false
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if signersFileLogin(line) == login {
+			removed = true
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if !removed {
+		postComment(ctx, gh, c, pr.GetNumber(), fmt.Sprintf("@%s %s is not in `%s`, nothing to revoke.", actor, login, path))
+		return CheckResult{}, nil
+	}
+
+	log.Info().Str("actor", actor).Str("login", login).Str("path", path).Str("branch", branch).Bool("dry_run", c.DryRun).Msg("Revoking signer")
+	msg := fmt.Sprintf("Remove %s from CLA signers (#%d)", login, pr.GetNumber())
+	if err := writeSignersFile(ctx, gh, c, path, branch, strings.Join(kept, ""), sha, msg); err != nil {
+		return CheckResult{}, fmt.Errorf("revoke: commit: %w", err)
+	}
+	postComment(ctx, gh, c, pr.GetNumber(), fmt.Sprintf("@%s removed %s from `%s`.", actor, login, path))
+
+	if login == strings.ToLower(pr.GetUser().GetLogin()) {
+		return HandlePullRequest(ctx, gh, c, pr)
+	}
+	return CheckResult{}, nil
+}
+
+// handleRefresh drops the cached signers, reloads every source and replies
+// with what each one returned, then re-runs the check. Maintainers use it
+// when a new signer is listed but the cache hasn't expired yet.
+func handleRefresh(ctx context.Context, gh *Client, c Config, pr *github.PullRequest, actor string) (CheckResult, error) {
+	ok, err := hasWriteAccess(ctx, gh, c, actor)
+	if err != nil {
+		return CheckResult{}, fmt.Errorf("permission: %w", err)
+	}
+	if !ok {
+		log.Warn().Str("actor", actor).Int("pr", pr.GetNumber()).Msg("Rejected refresh")
+		postComment(ctx, gh, c, pr.GetNumber(), fmt.Sprintf("@%s sorry, only maintainers with write access can refresh the signers.", actor))
+		return CheckResult{}, nil
+	}
+
+	cache := openSignerCache(c)
+	keys := cache.clear()
+	cache.save()
+	log.Info().Str("actor", actor).Strs("keys", keys).Msg("Cleared signer cache")
+
+	if c.Mode == modeCLA {
+		var b strings.Builder
+		fmt.Fprintf(&b, "@%s reloaded the signers:\n", actor)
+		for _, cc := range contextConfigs(c) {
+			contextHeading(&b, c, cc)
+			b.WriteString("| Source | Entries |\n|---|---|\n")
+			if _, err := loadSigners(ctx, gh, cc, pr.GetBase().GetRef(), func(source string, n int) {
+				fmt.Fprintf(&b, "| %s | %d |\n", source, n)
+			}); err != nil {
+				return CheckResult{}, fmt.Errorf("refresh: %w", err)
+			}
+		}
+		postComment(ctx, gh, c, pr.GetNumber(), b.String())
+	}
+	return HandlePullRequest(ctx, gh, c, pr)
+}
+
+// handleWhoami replies with how clabot sees the commenter: their normalized
+// login, the commit emails they used on the PR and, for each signer source,
+// whether and how they matched, grouped by status context. Only the
+// commenter's own emails are shown, and the check is left alone.
+func handleWhoami(ctx context.Context, gh *Client, c Config, pr *github.PullRequest, actor string) (CheckResult, error) {
+	if c.Mode != modeCLA {
+		log.Info().Str("actor", actor).Str("mode", c.Mode).Msg("Ignoring whoami command outside CLA mode")
+		return CheckResult{}, nil
+	}
+	commits, err := allCommits(ctx, gh, c, pr.GetNumber())
+	if err != nil {
+		return CheckResult{}, fmt.Errorf("list commits: %w", err)
+	}
+	// Collect every commit author, whatever CHECK_SCOPE says, to find the
+	// commenter's emails.
+	me := &Contributor{Login: actor}
+	for _, ct := range CollectContributors(actor, commits, scopeCommitAuthors, c.CheckIdentity) {
+		if ct.Login == actor {
+			me = ct
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "@%s here is how clabot sees you:\n\n- Login: `%s`\n", actor, actor)
+	if len(me.Emails) == 0 {
+		b.WriteString("- Commit emails on this PR: none\n")
+	} else {
+		fmt.Fprintf(&b, "- Commit emails on this PR: `%s`\n", strings.Join(me.Emails, "`, `"))
+	}
+	if !c.EmailMatch {
+		b.WriteString("- Email matching is off (`CLA_MATCH_EMAIL`), so only the login counts\n")
+	}
+	ref := pr.GetBase().GetRef()
+	for _, cc := range contextConfigs(c) {
+		contextHeading(&b, c, cc)
+		b.WriteString("| Source | Result |\n|---|---|\n")
+		for _, src := range singleSources(cc) {
+			s, err := LoadSigners(ctx, gh, src.c, ref)
+			reason, matched := s.match(me, c.EmailMatch, c.EmailFoldCase)
+			_, exempt := s.Exempt[actor]
+			result := "not listed"
+			switch {
+			case err != nil:
+				result = "error: " + err.Error()
+			case exempt:
+				result = "exempt"
+			case matched:
+				result = "matched via " + reason
+			case s.outdated(me, c.EmailMatch, c.EmailFoldCase):
+				result = "signed an older CLA, " + c.RequiredCLAVersion + " required"
+			}
+			fmt.Fprintf(&b, "| %s | %s |\n", src.name, result)
+		}
+	}
+	postComment(ctx, gh, c, pr.GetNumber(), b.String())
+	return CheckResult{}, nil
+}
+
+// signerSource is one configured signer source, with c narrowed to it.
+type signerSource struct {
+	name string
+	c    Config
+}
+
+// singleSources splits the top-level signer sources of c so they can be
+// loaded and matched one at a time.
+func singleSources(c Config) []signerSource {
+	var out []signerSource
+	for _, u := range c.GoogleSheetUrl {
+		out = append(out, signerSource{u, pathRule{GoogleSheetUrl: stringList{u}}.apply(c)})
+	}
+	for _, u := range c.SignersURL {
+		out = append(out, signerSource{u, pathRule{SignersURL: stringList{u}}.apply(c)})
+	}
+	for _, p := range c.SignersPath {
+		out = append(out, signerSource{p, pathRule{SignersPath: stringList{p}}.apply(c)})
+	}
+	for _, p := range c.SignersFileLocal {
+		out = append(out, signerSource{p, pathRule{SignersFileLocal: stringList{p}}.apply(c)})
+	}
+	return out
+}
+
+// signersFileTarget picks the signers file and branch that sign and revoke
+// commit to.
+func signersFileTarget(c Config, pr *github.PullRequest) (path, branch string, err error) {
+	path = c.SignPath
+	if path == "" && len(c.SignersPath) > 0 {
+		path = c.SignersPath[0]
+	}
+	if path == "" {
+		return "", "", fmt.Errorf("no signers file configured")
+	}
+	branch = c.SignBranch
+	if branch == "" {
+		branch = signersFileRef(c, pr.GetBase().GetRef())
+	}
+	return path, branch, nil
+}
+
+// readSignersFile returns the file's content and blob SHA on branch. A file
+// that doesn't exist yet reads as empty with a nil SHA.
+func readSignersFile(ctx context.Context, gh *Client, c Config, path, branch string) (string, *string, error) {
+	owner, name := c.signersRepo()
+	file, _, _, err := gh.Repositories.GetContents(ctx, owner, name, path, &github.RepositoryContentGetOptions{Ref: branch})
+	if isNotFound(err) {
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	content, err := file.GetContent()
+	if err != nil {
+		return "", nil, err
+	}
+	return content, file.SHA, nil
+}
+
+// writeSignersFile commits content to path on branch, creating the file when
+// sha is nil. An empty branch commits to the default branch. Nothing is
+// written in dry-run mode.
+func writeSignersFile(ctx context.Context, gh *Client, c Config, path, branch, content string, sha *string, message string) error {
+	if c.DryRun {
+		return nil
+	}
+	opts := &github.RepositoryContentFileOptions{
+		Message: github.String(message),
+		Content: []byte(content),
+		SHA:     sha,
+	}
+	if branch != "" {
+		opts.Branch = github.String(branch)
+	}
+	owner, name := c.signersRepo()
+	var err error
+	if sha == nil {
+		_, _, err = gh.Repositories.CreateFile(ctx, owner, name, path, opts)
+	} else {
+		_, _, err = gh.Repositories.UpdateFile(ctx, owner, name, path, opts)
+	}
+	return err
+}
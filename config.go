@@ -0,0 +1,679 @@
+package clabot
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is assembled from, in increasing order of precedence: built-in
+// defaults, the YAML (or JSON) file named by CLABOT_CONFIG, and environment
+// variables. An environment variable only overrides the file when it is set
+// to a non-empty value. Fields tagged `yaml:"-"` can only come from the
+// environment.
+type Config struct {
+	Forge               string          `yaml:"forge"`                 // code host: "github" (default) or "gitlab"
+	Mode                string          `yaml:"mode"`                  // "cla" (signer list, default), "dco" (Signed-off-by trailers) or "checkbox" (PR description)
+	CheckboxText        string          `yaml:"checkbox_text"`         // acknowledgement a checked box must contain in checkbox mode
+	RepoOwner           string          `yaml:"-"`                     // e.g. "your-org"
+	RepoName            string          `yaml:"-"`                     // e.g. "awesome-project"
+	EventName           string          `yaml:"-"`                     // pull_request or issue_comment
+	EventPath           string          `yaml:"-"`                     // path to the JSON payload created by Actions
+	StepSummaryPath     string          `yaml:"-"`                     // job summary file created by Actions
+	SignersPath         stringList      `yaml:"signers_path"`          // paths in repo: "cla-signers.txt"
+	RequireSignersFile  bool            `yaml:"require_signers_file"`  // fail instead of skipping a missing SignersPath file
+	SignersRef          string          `yaml:"signers_ref"`           // branch, tag or SHA to read SignersPath at; defaults to the PR's base branch
+	SignersRepo         string          `yaml:"signers_repo"`          // "owner/name" of a central repo holding SignersPath; defaults to the current repo
+	Token               string          `yaml:"-"`                     // GITHUB_TOKEN injected by Actions
+	GoogleSheetUrl      stringList      `yaml:"google_sheet_url"`      // CSV export URLs of public Google spreadsheets with signers
+	Triggers            stringList      `yaml:"bot_trigger"`           // mentions that start a command, e.g. "@cla-bot"
+	PRActions           stringList      `yaml:"pr_actions"`            // pull_request actions to check, e.g. "opened,synchronize"; empty checks all
+	SignersURL          stringList      `yaml:"signers_url"`           // JSON endpoints serving signers
+	SignersAuthHeader   string          `yaml:"-"`                     // Authorization header sent to SignersURL, e.g. "Bearer …"
+	SignersFileLocal    stringList      `yaml:"signers_file_local"`    // signers files on the runner, in the SignersPath format
+	SignersFormat       string          `yaml:"signers_format"`        // "plain" (default) or "csv" for repo and local signers files
+	PathSigners         []pathRule      `yaml:"path_signers"`          // extra signer sources required for PRs touching some paths; config file only
+	StatusContexts      []statusContext `yaml:"status_contexts"`       // separately reported checks with their own signer sources; config file only
+	StrictSigners       bool            `yaml:"signers_strict"`        // fail when a signer source returns nobody
+	AllowEmptySigners   bool            `yaml:"allow_empty_signers"`   // run with no signer source, so everyone not exempt is unsigned
+	RequiredCLAVersion  string          `yaml:"required_cla_version"`  // signers must have signed this CLA version or newer
+	CommentMsg          string          `yaml:"comment_msg"`           // Message to post as a comment; a text/template over messageData
+	SignURL             string          `yaml:"sign_url"`              // where to sign the CLA, exposed to templates as .SignersURL
+	CommentCooldown     time.Duration   `yaml:"comment_cooldown"`      // minimum time between updates to the bot comment
+	MentionAuthor       bool            `yaml:"mention_author"`        // @-mention contributors in the comment; false names them in plain text
+	IgnoreAuthors       authorSet       `yaml:"ignore_authors"`        // bots whose comments are ignored and whose PRs need no CLA
+	SkipBots            bool            `yaml:"skip_bots"`             // treat any login ending in [bot] like IgnoreAuthors
+	IncludeMergeCommits bool            `yaml:"include_merge_commits"` // also check the authors of merge commits
+	CheckScope          string          `yaml:"check_scope"`           // who must sign: pr-author, all-commit-authors or co-authors
+	CheckIdentity       string          `yaml:"check_identity"`        // which commit identity counts: author, committer or both
+	EmailMatch          bool            `yaml:"match_email"`           // also match signers by commit email
+	EmailFoldCase       bool            `yaml:"email_fold_case"`       // ignore case in the local part of emails too
+	ResolveEmails       bool            `yaml:"resolve_emails"`        // look up logins for commit emails not linked to an account
+	ResolveMode         string          `yaml:"resolve_comment_mode"`  // what to do with the failure comment once signed: keep, edit or delete
+	CommentOnSuccess    bool            `yaml:"comment_on_success"`    // post or update a success comment when the check passes
+	FailOnUnsigned      bool            `yaml:"fail_on_unsigned"`      // exit non-zero when the CLA check fails
+	ReportOnly          bool            `yaml:"report_only"`           // post unsigned results as success with a warning; still comment
+	GracePeriod         time.Duration   `yaml:"grace_period"`          // report unsigned PRs younger than this as pending instead of failed
+	StatusContext       string          `yaml:"status_context"`        // commit status context name, or the check run name
+	UseChecksAPI        bool            `yaml:"use_checks_api"`        // report a check run with a contributor summary instead of a commit status
+	LabelMode           string          `yaml:"label_mode"`            // off, both (labels and status) or only (labels instead of status)
+	LabelSigned         string          `yaml:"label_signed"`          // label for a passing check
+	LabelUnsigned       string          `yaml:"label_unsigned"`        // label for a failing check
+	DryRun              bool            `yaml:"dry_run"`               // log statuses and comments instead of posting them
+	ForceStatus         bool            `yaml:"force_status"`          // post commit statuses even when the current one already matches
+	StatusOnMergeSHA    bool            `yaml:"status_on_merge_sha"`   // also report on the PR's test merge commit
+	ExemptOrg           string          `yaml:"exempt_org"`            // members of this org don't need to sign
+	ExemptWriteAccess   bool            `yaml:"exempt_write_access"`   // skip internal (non-fork) PRs whose author has write access
+	ExemptTeams         stringList      `yaml:"exempt_teams"`          // team slugs in ExemptOrg; narrows the exemption to these teams
+	ExemptDomains       stringList      `yaml:"exempt_domains"`        // email domains whose contributors need no CLA, without counting as signed
+
+	// Google Sheet columns, each a zero-based index or a header name.
+	SheetLoginColumn   string        `yaml:"sheet_login_column"`
+	SheetEmailColumn   string        `yaml:"sheet_email_column"`   // optional
+	SheetVersionColumn string        `yaml:"sheet_version_column"` // optional; the CLA version each row signed
+	SheetHeaderNames   stringList    `yaml:"sheet_header_names"`   // cells that mark a row as a header
+	SheetPoll          time.Duration `yaml:"sheet_poll"`           // how long a comment-triggered check waits for the sheet to list a new signer; 0 disables
+	SheetTimeout       time.Duration `yaml:"sheet_timeout"`        // deadline for each attempt at fetching a sheet
+
+	SignersCacheTTL  time.Duration `yaml:"signers_cache_ttl"`  // cache loaded signers on disk; 0 disables
+	SignersCachePath string        `yaml:"signers_cache_path"` // defaults to the user cache dir
+	APICache         string        `yaml:"api_cache"`          // "off" (default), "memory" or "disk": revalidate GitHub GETs with ETags
+	APICachePath     string        `yaml:"api_cache_path"`     // directory for the disk API cache; defaults to the user cache dir
+
+	APIURL     string `yaml:"api_url"`     // GitHub REST endpoint, set by Actions; non-default for GHES
+	UploadURL  string `yaml:"upload_url"`  // GHES upload endpoint; derived from APIURL when empty
+	MaxRetries int    `yaml:"max_retries"` // retries for rate-limited or failed GitHub API calls
+
+	LookupConcurrency int `yaml:"lookup_concurrency"` // parallel membership and team lookups per check
+
+	// GitHub App credentials; used instead of Token when all are set.
+	AppID             int64  `yaml:"app_id"`
+	AppInstallationID int64  `yaml:"app_installation_id"`
+	AppPrivateKey     string `yaml:"-"` // PEM, optionally base64 encoded
+
+	WebhookSecret string `yaml:"-"`           // validates deliveries in server mode
+	ListenAddr    string `yaml:"listen_addr"` // server mode listen address
+
+	CheckClosedPRs bool `yaml:"check_closed_prs"` // act on comments on closed or merged PRs
+	SkipDrafts     bool `yaml:"skip_drafts"`      // defer the check on draft PRs until they are ready for review
+
+	SkipPaths stringList `yaml:"skip_paths"` // globs of files that alone don't need a CLA, e.g. docs/**
+
+	AuditLogPath string `yaml:"audit_log_path"` // append a JSON line per decision here
+	AuditLogURL  string `yaml:"audit_log_url"`  // POST each decision here as JSON
+
+	SlackWebhookURL string `yaml:"-"` // Slack incoming webhook notified of failing checks
+
+	MetricsPath string        `yaml:"metrics_path"` // append the run summary here instead of stdout
+	RunTimeout  time.Duration `yaml:"run_timeout"`  // deadline for handling one event
+
+	// "@cla-bot sign" appends the commenter to a signers file in the repo.
+	SelfSign      bool               `yaml:"self_sign"`
+	SignPath      string             `yaml:"sign_path"`       // defaults to the first SignersPath
+	SignBranch    string             `yaml:"sign_branch"`     // defaults to the PR's base branch
+	SignCommitMsg string             `yaml:"sign_commit_msg"` // text/template with .Login and .PRNumber
+	signCommitTpl *template.Template // compiled SignCommitMsg
+
+	commentTpl *template.Template // compiled CommentMsg
+
+	// Status descriptions, text/templates over messageData.
+	StatusSuccessDesc string             `yaml:"status_success_desc"` // replaces the built-in success descriptions when set
+	StatusFailureDesc string             `yaml:"status_failure_desc"` // defaults to "CLA not signed by ... ❌"
+	successDescTpl    *template.Template // compiled StatusSuccessDesc; nil when unset
+	failureDescTpl    *template.Template // compiled StatusFailureDesc
+
+	noComments bool // recheck-open without comments: statuses and labels only
+
+	Repos map[string]*repoConfig `yaml:"repos"` // per-repository overrides keyed by "owner/name"; config file only
+}
+
+// repoConfig overrides settings for one repository, so a server can cover an
+// org whose repos need different CLAs. Empty fields keep the top-level value.
+type repoConfig struct {
+	SignersPath    stringList `yaml:"signers_path"`
+	GoogleSheetUrl stringList `yaml:"google_sheet_url"`
+	CommentMsg     string     `yaml:"comment_msg"`
+	StatusContext  string     `yaml:"status_context"`
+
+	commentTpl *template.Template // compiled CommentMsg
+}
+
+// withRepoOverrides returns c with the repos entry for owner/name applied.
+func (c Config) withRepoOverrides(owner, name string) Config {
+	rc, ok := c.Repos[strings.ToLower(owner+"/"+name)]
+	if !ok {
+		return c
+	}
+	if len(rc.SignersPath) > 0 {
+		c.SignersPath = rc.SignersPath
+	}
+	if len(rc.GoogleSheetUrl) > 0 {
+		c.GoogleSheetUrl = rc.GoogleSheetUrl
+	}
+	if rc.commentTpl != nil {
+		c.CommentMsg, c.commentTpl = rc.CommentMsg, rc.commentTpl
+	}
+	if rc.StatusContext != "" {
+		c.StatusContext = rc.StatusContext
+	}
+	return c
+}
+
+// stringList is a list of strings that may also be written in YAML as a
+// single comma-separated string, mirroring the environment variable form.
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		*l = splitList(n.Value)
+		return nil
+	}
+	var list []string
+	if err := n.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// splitList splits a comma-separated value, dropping empty items.
+func splitList(raw string) []string {
+	var list []string
+	for _, v := range strings.Split(raw, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// authorSet is a set of lowercased logins, written as a list in YAML.
+type authorSet map[string]struct{}
+
+func (a *authorSet) UnmarshalYAML(n *yaml.Node) error {
+	var logins []string
+	if err := n.Decode(&logins); err != nil {
+		return err
+	}
+	*a = parseAuthors(logins)
+	return nil
+}
+
+func parseAuthors(logins []string) authorSet {
+	set := make(authorSet, len(logins))
+	for _, l := range logins {
+		if l = strings.ToLower(strings.TrimSpace(l)); l != "" {
+			set[l] = struct{}{}
+		}
+	}
+	return set
+}
+
+// LoadConfig builds the configuration from CLABOT_CONFIG and the environment.
+func LoadConfig() (Config, error) {
+	c := Config{
+		MaxRetries:        defaultMaxRetries,
+		LookupConcurrency: defaultLookupConcurrency,
+		CheckboxText:      defaultCheckboxText,
+		MentionAuthor:     true,
+		RunTimeout:        time.Minute,
+		SheetTimeout:      defaultSheetTimeout,
+		StatusContext:     "CLA check",
+		Triggers:          stringList{defaultTrigger},
+		ListenAddr:        defaultListenAddr,
+		LabelSigned:       "cla: signed",
+		LabelUnsigned:     "cla: not-signed",
+
+		SheetLoginColumn: "1",
+		SheetHeaderNames: stringList{"github", "login", "username", "github username", "github login"},
+	}
+
+	if path := os.Getenv("CLABOT_CONFIG"); path != "" {
+		if err := loadConfigFile(path, &c); err != nil {
+			return c, fmt.Errorf("config %s: %w", path, err)
+		}
+		log.Info().Str("path", path).Msg("Loaded config file")
+	}
+
+	// "<owner>/<repo>"; a fallback only, since Dispatch targets the repo named in
+	// the event payload.
+	c.RepoOwner, c.RepoName, _ = strings.Cut(os.Getenv("GITHUB_REPOSITORY"), "/")
+	c.EventName = os.Getenv("GITHUB_EVENT_NAME")
+	c.EventPath = os.Getenv("GITHUB_EVENT_PATH")
+	c.StepSummaryPath = os.Getenv("GITHUB_STEP_SUMMARY")
+	c.Token = os.Getenv("GITHUB_TOKEN")
+	c.AppPrivateKey = os.Getenv("GITHUB_APP_PRIVATE_KEY")
+	c.WebhookSecret = os.Getenv("WEBHOOK_SECRET")
+	c.SlackWebhookURL = os.Getenv("SLACK_WEBHOOK_URL")
+	c.SignersAuthHeader = os.Getenv("SIGNERS_AUTH_HEADER")
+
+	envList(&c.SignersPath, "SIGNERS_PATH")
+	envString(&c.SignersRef, "SIGNERS_REF")
+	envString(&c.SignersRepo, "SIGNERS_REPO")
+	envBool(&c.RequireSignersFile, "REQUIRE_SIGNERS_FILE")
+	envList(&c.GoogleSheetUrl, "GOOGLE_SHEET_URL")
+	envList(&c.SignersURL, "SIGNERS_URL")
+	envList(&c.SignersFileLocal, "SIGNERS_FILE_LOCAL")
+	envString(&c.SignersFormat, "SIGNERS_FORMAT")
+	envBool(&c.StrictSigners, "SIGNERS_STRICT")
+	envBool(&c.AllowEmptySigners, "ALLOW_EMPTY_SIGNERS")
+	envList(&c.Triggers, "BOT_TRIGGER")
+	envList(&c.PRActions, "PR_ACTIONS")
+	envString(&c.CommentMsg, "COMMENT_MSG")
+	envString(&c.StatusSuccessDesc, "STATUS_SUCCESS_DESC")
+	envString(&c.StatusFailureDesc, "STATUS_FAILURE_DESC")
+	envString(&c.SignURL, "CLA_SIGN_URL")
+	envDuration(&c.CommentCooldown, "COMMENT_COOLDOWN")
+	envBool(&c.MentionAuthor, "MENTION_AUTHOR")
+	envBool(&c.EmailMatch, "CLA_MATCH_EMAIL")
+	envBool(&c.EmailFoldCase, "CLA_EMAIL_FOLD_CASE")
+	envBool(&c.ResolveEmails, "RESOLVE_EMAILS")
+	envString(&c.ResolveMode, "RESOLVE_COMMENT_MODE")
+	envBool(&c.CommentOnSuccess, "COMMENT_ON_SUCCESS")
+	envString(&c.CheckScope, "CHECK_SCOPE")
+	envString(&c.CheckIdentity, "CHECK_IDENTITY")
+	envBool(&c.IncludeMergeCommits, "INCLUDE_MERGE_COMMITS")
+	envBool(&c.FailOnUnsigned, "FAIL_ON_UNSIGNED")
+	envBool(&c.ReportOnly, "REPORT_ONLY")
+	envDuration(&c.GracePeriod, "GRACE_PERIOD")
+	envString(&c.StatusContext, "STATUS_CONTEXT")
+	envBool(&c.UseChecksAPI, "USE_CHECKS_API")
+	envString(&c.LabelMode, "LABEL_MODE")
+	envString(&c.LabelSigned, "LABEL_SIGNED")
+	envString(&c.LabelUnsigned, "LABEL_UNSIGNED")
+	envBool(&c.DryRun, "DRY_RUN")
+	envBool(&c.ForceStatus, "FORCE_STATUS")
+	envBool(&c.StatusOnMergeSHA, "STATUS_ON_MERGE_SHA")
+	envBool(&c.SkipBots, "SKIP_BOTS")
+	envString(&c.ExemptOrg, "EXEMPT_ORG")
+	envList(&c.ExemptTeams, "EXEMPT_TEAMS")
+	envList(&c.ExemptDomains, "EXEMPT_DOMAINS")
+	envBool(&c.ExemptWriteAccess, "EXEMPT_WRITE_ACCESS")
+	envString(&c.SheetLoginColumn, "SHEET_LOGIN_COLUMN")
+	envString(&c.SheetEmailColumn, "SHEET_EMAIL_COLUMN")
+	envString(&c.SheetVersionColumn, "SHEET_VERSION_COLUMN")
+	envList(&c.SheetHeaderNames, "SHEET_HEADER_NAMES")
+	envDuration(&c.SheetPoll, "SHEET_POLL")
+	envDuration(&c.SheetTimeout, "SHEET_TIMEOUT")
+	envDuration(&c.SignersCacheTTL, "SIGNERS_CACHE_TTL")
+	envString(&c.SignersCachePath, "SIGNERS_CACHE_PATH")
+	envString(&c.APICache, "API_CACHE")
+	envString(&c.APICachePath, "API_CACHE_PATH")
+	envString(&c.Mode, "MODE")
+	envString(&c.Forge, "FORGE")
+	envString(&c.CheckboxText, "CHECKBOX_TEXT")
+	envBool(&c.SelfSign, "SELF_SIGN")
+	envString(&c.SignPath, "SIGN_PATH")
+	envString(&c.SignBranch, "SIGN_BRANCH")
+	envString(&c.SignCommitMsg, "SIGN_COMMIT_MSG")
+	envBool(&c.CheckClosedPRs, "CHECK_CLOSED_PRS")
+	envBool(&c.SkipDrafts, "SKIP_DRAFTS")
+	envList(&c.SkipPaths, "SKIP_PATHS")
+	envString(&c.ListenAddr, "LISTEN_ADDR")
+	envString(&c.AuditLogPath, "AUDIT_LOG_PATH")
+	envString(&c.AuditLogURL, "AUDIT_LOG_URL")
+	envString(&c.MetricsPath, "METRICS_PATH")
+	envDuration(&c.RunTimeout, "RUN_TIMEOUT")
+	envString(&c.APIURL, "GITHUB_API_URL")
+	envString(&c.UploadURL, "GITHUB_UPLOAD_URL")
+	envInt(&c.MaxRetries, "GITHUB_MAX_RETRIES")
+	envInt(&c.LookupConcurrency, "LOOKUP_CONCURRENCY")
+	envInt64(&c.AppID, "GITHUB_APP_ID")
+	envInt64(&c.AppInstallationID, "GITHUB_APP_INSTALLATION_ID")
+
+	if raw := os.Getenv("BOT_IGNORE_AUTHORS"); raw != "" {
+		c.IgnoreAuthors = parseAuthors(strings.Split(raw, ","))
+	}
+	if c.IgnoreAuthors == nil {
+		c.IgnoreAuthors = parseAuthors([]string{actionsBot})
+	}
+
+	if c.RunTimeout <= 0 {
+		log.Warn().Dur("value", c.RunTimeout).Msg("Ignoring non-positive run timeout")
+		c.RunTimeout = time.Minute
+	}
+	if c.SheetTimeout <= 0 {
+		log.Warn().Dur("value", c.SheetTimeout).Msg("Ignoring non-positive sheet timeout")
+		c.SheetTimeout = defaultSheetTimeout
+	}
+
+	if c.MaxRetries < 0 {
+		log.Warn().Int("value", c.MaxRetries).Msg("Ignoring negative max retries")
+		c.MaxRetries = defaultMaxRetries
+	}
+	if c.LookupConcurrency < 1 {
+		log.Warn().Int("value", c.LookupConcurrency).Msg("Ignoring non-positive lookup concurrency")
+		c.LookupConcurrency = defaultLookupConcurrency
+	}
+
+	var triggers stringList
+	for _, t := range c.Triggers {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			triggers = append(triggers, t)
+		}
+	}
+	if len(triggers) == 0 {
+		triggers = stringList{defaultTrigger}
+	}
+	c.Triggers = triggers
+	for i, a := range c.PRActions {
+		c.PRActions[i] = strings.ToLower(a)
+	}
+	for i, d := range c.ExemptDomains {
+		c.ExemptDomains[i] = strings.TrimPrefix(strings.ToLower(d), "@")
+	}
+
+	if c.CommentMsg == "" {
+		c.CommentMsg = "Please sign the CLA and then comment `" + c.Triggers[0] + " check` on this PR."
+	}
+	var err error
+	if c.commentTpl, err = parseMessage("COMMENT_MSG", c.CommentMsg, c.MentionAuthor); err != nil {
+		return c, err
+	}
+
+	if c.StatusSuccessDesc != "" {
+		if c.successDescTpl, err = parseDescription("STATUS_SUCCESS_DESC", c.StatusSuccessDesc); err != nil {
+			return c, err
+		}
+	}
+	if c.StatusFailureDesc == "" {
+		c.StatusFailureDesc = defaultFailureDesc
+	}
+	if c.failureDescTpl, err = parseDescription("STATUS_FAILURE_DESC", c.StatusFailureDesc); err != nil {
+		return c, err
+	}
+
+	repos := make(map[string]*repoConfig, len(c.Repos))
+	for key, rc := range c.Repos {
+		owner, name, ok := strings.Cut(key, "/")
+		if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+			return c, fmt.Errorf("repos: invalid key %q, expected owner/name", key)
+		}
+		if rc == nil {
+			rc = &repoConfig{}
+		}
+		if rc.CommentMsg != "" {
+			if rc.commentTpl, err = parseMessage("repos."+key+".comment_msg", rc.CommentMsg, c.MentionAuthor); err != nil {
+				return c, err
+			}
+		}
+		repos[strings.ToLower(key)] = rc
+	}
+	c.Repos = repos
+
+	if c.SignCommitMsg == "" {
+		c.SignCommitMsg = "Add {{.Login}} to CLA signers (#{{.PRNumber}})"
+	}
+	tpl, err := template.New("SIGN_COMMIT_MSG").Parse(c.SignCommitMsg)
+	if err != nil {
+		return c, err
+	}
+	c.signCommitTpl = tpl
+
+	if err := validSkipPaths(c.SkipPaths); err != nil {
+		return c, err
+	}
+
+	c.Forge = strings.ToLower(c.Forge)
+	switch c.Forge {
+	case "":
+		c.Forge = forgeGitHub
+	case forgeGitHub:
+	case forgeGitLab:
+		if err := c.useGitLab(); err != nil {
+			return c, err
+		}
+	default:
+		return c, fmt.Errorf("unknown FORGE %q", c.Forge)
+	}
+
+	c.Mode = strings.ToLower(c.Mode)
+	switch c.Mode {
+	case "":
+		c.Mode = modeCLA
+	case modeCLA, modeDCO, modeCheckbox:
+	default:
+		return c, fmt.Errorf("unknown MODE %q", c.Mode)
+	}
+	if c.CheckboxText = strings.TrimSpace(c.CheckboxText); c.CheckboxText == "" {
+		c.CheckboxText = defaultCheckboxText
+	}
+
+	c.CheckScope = strings.ToLower(c.CheckScope)
+	switch c.CheckScope {
+	case "":
+		c.CheckScope = scopeCommitAuthors
+	case scopePRAuthor, scopeCommitAuthors, scopeCoAuthors:
+	default:
+		return c, fmt.Errorf("unknown CHECK_SCOPE %q", c.CheckScope)
+	}
+
+	c.SignersFormat = strings.ToLower(c.SignersFormat)
+	switch c.SignersFormat {
+	case "":
+		c.SignersFormat = signersFormatPlain
+	case signersFormatPlain, signersFormatCSV:
+	default:
+		return c, fmt.Errorf("unknown SIGNERS_FORMAT %q", c.SignersFormat)
+	}
+
+	c.LabelMode = strings.ToLower(c.LabelMode)
+	switch c.LabelMode {
+	case "":
+		c.LabelMode = labelOff
+	case labelOff, labelBoth, labelOnly:
+	default:
+		return c, fmt.Errorf("unknown LABEL_MODE %q", c.LabelMode)
+	}
+
+	c.APICache = strings.ToLower(c.APICache)
+	switch c.APICache {
+	case "":
+		c.APICache = apiCacheOff
+	case apiCacheOff, apiCacheMemory, apiCacheDisk:
+	default:
+		return c, fmt.Errorf("unknown API_CACHE %q", c.APICache)
+	}
+
+	c.CheckIdentity = strings.ToLower(c.CheckIdentity)
+	switch c.CheckIdentity {
+	case "":
+		c.CheckIdentity = identityAuthor
+	case identityAuthor, identityCommitter, identityBoth:
+	default:
+		return c, fmt.Errorf("unknown CHECK_IDENTITY %q", c.CheckIdentity)
+	}
+
+	for i, r := range c.PathSigners {
+		if err := r.validate(); err != nil {
+			return c, fmt.Errorf("path_signers[%d]: %w", i, err)
+		}
+	}
+	for i, s := range c.StatusContexts {
+		if err := s.validate(); err != nil {
+			return c, fmt.Errorf("status_contexts[%d]: %w", i, err)
+		}
+	}
+
+	if c.SignersRepo != "" {
+		owner, name, ok := strings.Cut(c.SignersRepo, "/")
+		if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+			return c, fmt.Errorf("invalid SIGNERS_REPO %q, expected owner/name", c.SignersRepo)
+		}
+	}
+
+	c.ResolveMode = strings.ToLower(c.ResolveMode)
+	switch c.ResolveMode {
+	case resolveKeep, resolveEdit, resolveDelete:
+	default:
+		if c.ResolveMode != "" {
+			log.Warn().Str("mode", c.ResolveMode).Msg("Unknown RESOLVE_COMMENT_MODE, keeping comments")
+		}
+		c.ResolveMode = resolveKeep
+	}
+
+	// Repos entries may bring their own sources; a repo without any is still
+	// caught per PR.
+	if c.lacksSignerSources() && len(c.Repos) == 0 {
+		return c, ErrNoSignerSource
+	}
+
+	if c.ReportOnly {
+		log.Warn().Msg("REPORT_ONLY is active: unsigned PRs get a passing status, only the comment and description tell")
+	}
+	return c, nil
+}
+
+// lacksSignerSources reports whether c checks CLAs without anywhere to load
+// signers from, which would mark everyone unsigned.
+func (c Config) lacksSignerSources() bool {
+	return c.Mode == modeCLA && !c.AllowEmptySigners && !c.hasSignerSources() && len(c.PathSigners)+len(c.StatusContexts) == 0
+}
+
+// useGitLab reads the GitLab token, endpoint and project and rejects the
+// settings that need GitHub-only APIs. In GitLab CI the predefined
+// CI_API_V4_URL and CI_PROJECT_PATH variables fill in the endpoint and
+// project.
+func (c *Config) useGitLab() error {
+	if t := os.Getenv("GITLAB_TOKEN"); t != "" {
+		c.Token = t
+	}
+	envString(&c.APIURL, "CI_API_V4_URL")
+	envString(&c.APIURL, "GITLAB_API_URL")
+	if base := strings.TrimSuffix(c.APIURL, "/"); base == "" || base == publicAPIURL {
+		c.APIURL = defaultGitLabAPIURL
+	}
+	if c.RepoOwner == "" {
+		if p := os.Getenv("CI_PROJECT_PATH"); strings.Contains(p, "/") {
+			i := strings.LastIndex(p, "/")
+			c.RepoOwner, c.RepoName = p[:i], p[i+1:]
+		}
+	}
+
+	for _, s := range []struct {
+		set  bool
+		name string
+	}{
+		{c.usesApp(), "GitHub App credentials"},
+		{c.UseChecksAPI, "USE_CHECKS_API"},
+		{c.ExemptOrg != "", "EXEMPT_ORG"},
+		{c.ResolveEmails, "RESOLVE_EMAILS"},
+	} {
+		if s.set {
+			return fmt.Errorf("%s can't be used with FORGE=gitlab", s.name)
+		}
+	}
+	return nil
+}
+
+// usesApp reports whether GitHub App credentials are configured, in which
+// case clabot authenticates as the App installation instead of with Token.
+func (c Config) usesApp() bool {
+	return c.AppID != 0 && c.AppInstallationID != 0 && c.AppPrivateKey != ""
+}
+
+// signersRepo returns the repository SignersPath is read from and signed into.
+func (c Config) signersRepo() (owner, name string) {
+	if owner, name, ok := strings.Cut(c.SignersRepo, "/"); ok {
+		return owner, name
+	}
+	return c.RepoOwner, c.RepoName
+}
+
+// loadConfigFile decodes a YAML file into c. JSON is valid YAML, so a JSON
+// file works as well. Unknown keys are rejected with their line, since a
+// misspelled key would otherwise silently do nothing.
+func loadConfigFile(path string, c *Config) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// envString overrides dst with the named variable when it is set.
+func envString(dst *string, name string) {
+	if v := os.Getenv(name); v != "" {
+		*dst = v
+	}
+}
+
+// envList overrides dst with the named comma-separated variable when it is
+// set, dropping empty items.
+func envList(dst *stringList, name string) {
+	if raw := os.Getenv(name); raw != "" {
+		*dst = splitList(raw)
+	}
+}
+
+// envBool overrides dst with the named variable when it is set to a valid
+// boolean.
+func envBool(dst *bool, name string) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		log.Warn().Str("var", name).Str("value", raw).Msg("Ignoring invalid boolean")
+		return
+	}
+	*dst = v
+}
+
+// envInt overrides dst with the named variable when it is set to a valid
+// integer.
+func envInt(dst *int, name string) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		log.Warn().Str("var", name).Str("value", raw).Msg("Ignoring invalid integer")
+		return
+	}
+	*dst = v
+}
+
+// envDuration overrides dst with the named variable when it is set to a
+// valid duration such as "10m".
+func envDuration(dst *time.Duration, name string) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return
+	}
+	v, err := time.ParseDuration(raw)
+	if err != nil {
+		log.Warn().Str("var", name).Str("value", raw).Msg("Ignoring invalid duration")
+		return
+	}
+	*dst = v
+}
+
+// envInt64 is envInt for int64 values such as GitHub IDs.
+func envInt64(dst *int64, name string) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return
+	}
+	v, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		log.Warn().Str("var", name).Str("value", raw).Msg("Ignoring invalid integer")
+		return
+	}
+	*dst = v
+}
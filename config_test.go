@@ -0,0 +1,53 @@
+package clabot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "clabot.yml")
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigFileUnknownKey(t *testing.T) {
+	path := writeConfig(t, "google_sheet_url: https://example.com/sheet\nsigners_pth: .github/signers.txt\n")
+
+	var c Config
+	err := loadConfigFile(path, &c)
+	if err == nil {
+		t.Fatal("loadConfigFile accepted an unknown key")
+	}
+	for _, want := range []string{"line 2", "signers_pth"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestLoadConfigFileKnownKeys(t *testing.T) {
+	path := writeConfig(t, "google_sheet_url: https://example.com/sheet\nsigners_path: .github/signers.txt\n")
+
+	var c Config
+	if err := loadConfigFile(path, &c); err != nil {
+		t.Fatalf("loadConfigFile: %v", err)
+	}
+	if len(c.SignersPath) != 1 || c.SignersPath[0] != ".github/signers.txt" {
+		t.Errorf("SignersPath = %q, want [.github/signers.txt]", c.SignersPath)
+	}
+}
+
+func TestLoadConfigRejectsUnknownKey(t *testing.T) {
+	t.Setenv("CLABOT_CONFIG", writeConfig(t, "google_sheet_url: https://example.com/sheet\nmdoe: dco\n"))
+
+	_, err := LoadConfig()
+	if err == nil || !strings.Contains(err.Error(), "mdoe") {
+		t.Fatalf("LoadConfig error = %v, want one naming mdoe", err)
+	}
+}
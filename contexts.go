@@ -0,0 +1,121 @@
+package clabot
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/go-github/v58/github"
+)
+
+// statusContext is a CLA check reported under its own status context, such
+// as "CLA: individual" next to "CLA: corporate", passing or failing on its
+// own signer sources. The source keys mirror the top-level ones.
+type statusContext struct {
+	Context          string     `yaml:"context"`
+	SignersPath      stringList `yaml:"signers_path"`
+	GoogleSheetUrl   stringList `yaml:"google_sheet_url"`
+	SignersURL       stringList `yaml:"signers_url"`
+	SignersFileLocal stringList `yaml:"signers_file_local"`
+}
+
+// validate rejects contexts without a name or any signer source.
+func (s statusContext) validate() error {
+	if s.Context == "" {
+		return fmt.Errorf("no context")
+	}
+	if !s.apply(Config{}).hasSignerSources() {
+		return fmt.Errorf("context %q has no signer sources", s.Context)
+	}
+	return nil
+}
+
+// apply returns c narrowed to s: its context name and signer sources. The
+// comment is left to checkContexts, which writes one for all contexts.
+func (s statusContext) apply(c Config) Config {
+	c = pathRule{
+		SignersPath:      s.SignersPath,
+		GoogleSheetUrl:   s.GoogleSheetUrl,
+		SignersURL:       s.SignersURL,
+		SignersFileLocal: s.SignersFileLocal,
+	}.apply(c)
+	c.StatusContext = s.Context
+	c.StatusContexts = nil
+	c.PathSigners = nil
+	c.noComments = true
+	return c
+}
+
+// contextConfigs returns c narrowed to each STATUS_CONTEXTS entry, or just c
+// when there are none, for the commands that report per context.
+func contextConfigs(c Config) []Config {
+	if len(c.StatusContexts) == 0 {
+		return []Config{c}
+	}
+	out := make([]Config, len(c.StatusContexts))
+	for i, sc := range c.StatusContexts {
+		out[i] = sc.apply(c)
+	}
+	return out
+}
+
+// contextHeading starts the section of a command reply about cc: a blank
+// line, and a heading naming cc's status context when there are several.
+func contextHeading(b *strings.Builder, c, cc Config) {
+	if len(c.StatusContexts) == 0 {
+		b.WriteString("\n")
+		return
+	}
+	fmt.Fprintf(b, "\n#### %s\n\n", cc.StatusContext)
+}
+
+// checkContexts matches the contributors x left pending against each
+// STATUS_CONTEXTS entry's signers, each posting its own status, then keeps a
+// single comment naming everyone who is missing from any of them. It returns
+// the worst result.
+func checkContexts(ctx context.Context, gh *Client, c Config, pr *github.PullRequest, x exemptions) (CheckResult, error) {
+	var res CheckResult
+	var worst evaluation
+	var unsigned []string
+	for i, sc := range c.StatusContexts {
+		cc := sc.apply(c)
+		e, err := evaluateSigners(ctx, gh, cc, pr, x)
+		if err != nil {
+			return CheckResult{}, fmt.Errorf("status context %s: %w", sc.Context, err)
+		}
+		r, err := reportEvaluation(ctx, gh, cc, pr, e)
+		if err != nil {
+			return CheckResult{}, fmt.Errorf("status context %s: %w", sc.Context, err)
+		}
+		for _, u := range r.UnsignedLogins {
+			if !slices.Contains(unsigned, u) {
+				unsigned = append(unsigned, u)
+			}
+		}
+		if i == 0 || r.State > res.State {
+			res, worst = r, e
+		}
+	}
+	metricsFrom(ctx).recordCheck(pr.GetNumber(), worst.signed, len(worst.unsigned), worst.exempted())
+	res.UnsignedLogins = unsigned
+
+	if len(unsigned) == 0 {
+		resolveComment(ctx, gh, c, pr.GetNumber())
+		return res, nil
+	}
+	msg, err := render(c.commentTpl, messageData{
+		Author:         res.Author,
+		PRNumber:       pr.GetNumber(),
+		UnsignedLogins: unsigned,
+		SignersURL:     c.SignURL,
+	})
+	if err != nil {
+		return res, fmt.Errorf("comment: %w", err)
+	}
+	upsertComment(ctx, gh, c, pr.GetNumber(), pr.GetHead().GetSHA(), msg)
+	if res.State == ResultUnsigned {
+		notifySlack(ctx, c, pr, unsigned)
+	}
+	return res, nil
+}
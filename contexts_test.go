@@ -0,0 +1,172 @@
+package clabot
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-github/v58/github"
+)
+
+func TestCheckContexts(t *testing.T) {
+	f := newFakeGitHub()
+	f.files["individual.txt"] = "octocat\n"
+	f.files["corporate.txt"] = "monalisa\n"
+	f.commits = []*github.RepositoryCommit{commit("octocat", "octocat@example.com"), commit("hubot", "hubot@example.com")}
+	c := testConfig(t)
+	c.StatusContexts = []statusContext{
+		{Context: "CLA: individual", SignersPath: stringList{"individual.txt"}},
+		{Context: "CLA: corporate", SignersPath: stringList{"corporate.txt"}},
+	}
+
+	res, err := HandlePullRequest(context.Background(), f.client(), c, testPR("octocat"))
+	if err != nil {
+		t.Fatalf("HandlePullRequest: %v", err)
+	}
+	if res.State != ResultUnsigned {
+		t.Errorf("state = %v, want unsigned", res.State)
+	}
+	if want := []string{"hubot", "octocat"}; !slices.Equal(slices.Sorted(slices.Values(res.UnsignedLogins)), want) {
+		t.Errorf("unsigned = %q, want %q", res.UnsignedLogins, want)
+	}
+
+	// Newest first: each context's result, then one pending per context.
+	got := make(map[string][]string)
+	for _, s := range slices.Backward(f.statuses) {
+		got[s.GetContext()] = append(got[s.GetContext()], s.GetState())
+	}
+	for _, ctx := range []string{"CLA: individual", "CLA: corporate"} {
+		if want := []string{"pending", "failure"}; !slices.Equal(got[ctx], want) {
+			t.Errorf("%s statuses = %q, want %q", ctx, got[ctx], want)
+		}
+	}
+	if len(got) != 2 {
+		t.Errorf("statuses posted under %d contexts, want 2", len(got))
+	}
+	if len(f.comments) != 1 {
+		t.Errorf("%d comments, want 1", len(f.comments))
+	}
+}
+
+// countingOrgs answers membership lookups and counts them.
+type countingOrgs struct {
+	organizationsAPI
+	members map[string]bool
+	calls   *atomic.Int32
+}
+
+func (o countingOrgs) IsMember(ctx context.Context, org, user string) (bool, *github.Response, error) {
+	o.calls.Add(1)
+	return o.members[user], ok(), nil
+}
+
+func TestCheckContextsLooksUpExemptionsOnce(t *testing.T) {
+	f := newFakeGitHub()
+	f.files["individual.txt"] = "hubot\n"
+	f.files["corporate.txt"] = "hubot\n"
+	f.commits = []*github.RepositoryCommit{commit("octocat", "octocat@example.com"), commit("hubot", "hubot@example.com")}
+	c := testConfig(t)
+	c.ExemptOrg = "octo-org"
+	c.StatusContexts = []statusContext{
+		{Context: "CLA: individual", SignersPath: stringList{"individual.txt"}},
+		{Context: "CLA: corporate", SignersPath: stringList{"corporate.txt"}},
+	}
+	var calls atomic.Int32
+	gh := f.client()
+	gh.Organizations = countingOrgs{members: map[string]bool{"octocat": true}, calls: &calls}
+
+	res, err := HandlePullRequest(context.Background(), gh, c, testPR("octocat"))
+	if err != nil {
+		t.Fatalf("HandlePullRequest: %v", err)
+	}
+	if res.State != ResultSigned {
+		t.Errorf("state = %v, want signed", res.State)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("%d membership lookups for 2 contributors and 2 contexts, want 2", n)
+	}
+}
+
+// writers grants write access to every login.
+type writers struct{ fakeRepositories }
+
+func (writers) GetPermissionLevel(ctx context.Context, owner, repo, user string) (*github.RepositoryPermissionLevel, *github.Response, error) {
+	return &github.RepositoryPermissionLevel{Permission: github.String("write")}, ok(), nil
+}
+
+func TestCommandsPerContext(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(context.Context, *Client, Config, *github.PullRequest, string) (CheckResult, error)
+		want []string // in the reply, in order
+	}{
+		{name: "status", run: handleStatus, want: []string{"#### CLA: individual", "octocat", "#### CLA: corporate", "octocat"}},
+		{name: "whoami", run: handleWhoami, want: []string{"#### CLA: individual", "| individual.txt | matched via login octocat |", "#### CLA: corporate", "| corporate.txt | not listed |"}},
+		{name: "refresh", run: handleRefresh, want: []string{"#### CLA: individual", "| individual.txt | 1 |", "#### CLA: corporate", "| corporate.txt | 2 |"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeGitHub()
+			f.files["individual.txt"] = "octocat\n"
+			f.files["corporate.txt"] = "monalisa\nhubot\n"
+			f.commits = []*github.RepositoryCommit{commit("octocat", "octocat@example.com")}
+			c := testConfig(t)
+			c.SignersPath = nil // only the contexts have sources
+			c.SignersCacheTTL = 0
+			c.StatusContexts = []statusContext{
+				{Context: "CLA: individual", SignersPath: stringList{"individual.txt"}},
+				{Context: "CLA: corporate", SignersPath: stringList{"corporate.txt"}},
+			}
+			gh := f.client()
+			gh.Repositories = writers{fakeRepositories{f: f}}
+
+			if _, err := tt.run(context.Background(), gh, c, testPR("octocat"), "octocat"); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if len(f.comments) == 0 {
+				t.Fatal("no reply")
+			}
+			reply := f.comments[0].GetBody()
+			rest := reply
+			for _, w := range tt.want {
+				i := strings.Index(rest, w)
+				if i < 0 {
+					t.Fatalf("reply lacks %q after the previous parts:\n%s", w, reply)
+				}
+				rest = rest[i+len(w):]
+			}
+		})
+	}
+}
+
+func TestCurrentStateContexts(t *testing.T) {
+	status := func(context, state string) *github.RepoStatus {
+		return &github.RepoStatus{Context: github.String(context), State: github.String(state)}
+	}
+	tests := []struct {
+		name     string
+		statuses []*github.RepoStatus // newest first
+		want     string
+	}{
+		{name: "both pass", statuses: []*github.RepoStatus{status("CLA: individual", "success"), status("CLA: corporate", "success")}, want: "success"},
+		{name: "one fails", statuses: []*github.RepoStatus{status("CLA: individual", "success"), status("CLA: corporate", "failure")}, want: "failure"},
+		{name: "newest wins", statuses: []*github.RepoStatus{status("CLA: corporate", "success"), status("CLA: individual", "success"), status("CLA: corporate", "failure")}, want: "success"},
+		{name: "one missing", statuses: []*github.RepoStatus{status("CLA: individual", "success"), status("CLA check", "success")}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeGitHub()
+			f.statuses = tt.statuses
+			c := testConfig(t)
+			c.StatusContexts = []statusContext{
+				{Context: "CLA: individual", SignersPath: stringList{"individual.txt"}},
+				{Context: "CLA: corporate", SignersPath: stringList{"corporate.txt"}},
+			}
+			if got := currentState(context.Background(), f.client(), c, "abc123"); got != tt.want {
+				t.Errorf("currentState = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package clabot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v58/github"
+	"github.com/rs/zerolog/log"
+)
+
+// Values for MODE.
+const (
+	modeCLA      = "cla"
+	modeDCO      = "dco"
+	modeCheckbox = "checkbox"
+)
+
+// signoffRe matches a "Signed-off-by: Name <email>" trailer.
+var signoffRe = regexp.MustCompile(`(?im)^signed-off-by:\s*(.*?)\s*<([^>]+)>\s*$`)
+
+// hasSignoff reports whether msg carries a sign-off for email.
+func hasSignoff(msg, email string) bool {
+	for _, m := range signoffRe.FindAllStringSubmatch(msg, -1) {
+		if strings.EqualFold(strings.TrimSpace(m[2]), email) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkDCO requires every commit on the PR to be signed off by its author
+// under the Developer Certificate of Origin. Commits by bots are skipped, and
+// so are merge commits, which "Update branch" creates without a sign-off.
+func checkDCO(ctx context.Context, gh *Client, c Config, pr *github.PullRequest, commits []*github.RepositoryCommit) (CheckResult, error) {
+	sha := pr.GetHead().GetSHA()
+	commits = withoutMerges(commits)
+
+	var missing []string
+	for _, rc := range commits {
+		if isBot(c, strings.ToLower(rc.GetAuthor().GetLogin())) {
+			continue
+		}
+		email := rc.GetCommit().GetAuthor().GetEmail()
+		if hasSignoff(rc.GetCommit().GetMessage(), email) {
+			continue
+		}
+		short := rc.GetSHA()
+		if len(short) > 7 {
+			short = short[:7]
+		}
+		log.Info().Str("commit", short).Str("email", email).Msg("Commit missing DCO sign-off")
+		missing = append(missing, short)
+	}
+
+	metricsFrom(ctx).recordCheck(pr.GetNumber(), len(commits)-len(missing), len(missing), 0)
+	author := strings.ToLower(pr.GetUser().GetLogin())
+	if len(missing) == 0 {
+		res := CheckResult{State: ResultSigned, Description: "All commits signed off ✔️", Author: author}
+		postStatus(ctx, gh, c, pr, "success", res.Description, "")
+		resolveComment(ctx, gh, c, pr.GetNumber())
+		return res, nil
+	}
+
+	res := CheckResult{State: ResultUnsigned, Author: author}
+	var state string
+	state, res.Description = unsignedStatus(c, "Missing sign-off on "+strings.Join(missing, ", ")+" ❌")
+	postStatus(ctx, gh, c, pr, state, res.Description, "")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s these commits are missing a `Signed-off-by:` line matching the commit author's email:\n\n", mention(c, pr.GetUser().GetLogin()))
+	for _, m := range missing {
+		fmt.Fprintf(&b, "- %s\n", m)
+	}
+	b.WriteString("\nPlease sign them off with `git rebase --signoff " + pr.GetBase().GetSHA() + "` and force-push.")
+	upsertComment(ctx, gh, c, pr.GetNumber(), sha, b.String())
+	return res, nil
+}
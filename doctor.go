@@ -0,0 +1,92 @@
+package clabot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Doctor checks that the configuration works before clabot sees a real PR:
+// the token, the repository and every signer source. It writes a PASS/FAIL
+// line per check to w and reports whether all of them passed.
+func Doctor(ctx context.Context, gh *Client, c Config, w io.Writer) bool {
+	ok := true
+	report := func(name string, err error, detail string) {
+		if err != nil {
+			ok = false
+			fmt.Fprintf(w, "FAIL %s: %v\n", name, err)
+			return
+		}
+		fmt.Fprintf(w, "PASS %s: %s\n", name, detail)
+	}
+
+	// Installation tokens, including the Actions GITHUB_TOKEN, can't read the
+	// authenticated user; a rate limit lookup still proves the token works.
+	if u, _, err := gh.Users.Get(ctx, ""); err == nil {
+		report("token", nil, "authenticated as "+u.GetLogin())
+	} else if _, _, rerr := gh.RateLimit.Get(ctx); rerr == nil {
+		report("token", nil, "valid installation token")
+	} else {
+		report("token", err, "")
+	}
+
+	switch missing, verified, err := CheckPermissions(ctx, gh, c); {
+	case err != nil:
+		report("permissions", err, "")
+	case len(missing) > 0:
+		report("permissions", fmt.Errorf("missing %s", strings.Join(missing, ", ")), "")
+	case !verified:
+		report("permissions", nil, "can't be inspected for this token type; grant statuses, pull-requests and contents access")
+	default:
+		report("permissions", nil, "all required permissions granted")
+	}
+
+	if c.RepoOwner == "" || c.RepoName == "" {
+		report("repository", fmt.Errorf("GITHUB_REPOSITORY is not set"), "")
+		return false
+	}
+	repo, _, err := gh.Repositories.Get(ctx, c.RepoOwner, c.RepoName)
+	if err != nil {
+		report("repository", err, "")
+		return false
+	}
+	report("repository", nil, repo.GetFullName())
+
+	if c.SignersRepo != "" && len(c.SignersPath) > 0 {
+		owner, name := c.signersRepo()
+		repo, _, err = gh.Repositories.Get(ctx, owner, name)
+		if err != nil {
+			report("signers repository", err, "")
+			return false
+		}
+		report("signers repository", nil, repo.GetFullName())
+	}
+	ref := c.SignersRef
+	if ref == "" {
+		ref = repo.GetDefaultBranch()
+	}
+	for _, path := range c.SignersPath {
+		s, err := loadSignersGithub(ctx, gh, c, path, ref)
+		report("signers file "+path, err, fmt.Sprintf("%d signers on %s", s.size(), ref))
+	}
+	for _, url := range c.GoogleSheetUrl {
+		s, err := loadSignersFromGoogleSheet(ctx, c, url)
+		if err == nil && s.size() == 0 {
+			err = fmt.Errorf("no signers parsed; check the export URL and SHEET_LOGIN_COLUMN")
+		}
+		report("google sheet "+url, err, fmt.Sprintf("%d signers", s.size()))
+	}
+	for _, url := range c.SignersURL {
+		s, err := loadSignersFromURL(ctx, c, url)
+		report("signers url "+url, err, fmt.Sprintf("%d signers", s.size()))
+	}
+	for _, path := range c.SignersFileLocal {
+		s, err := loadSignersLocal(path, c.SignersFormat)
+		report("local signers file "+path, err, fmt.Sprintf("%d signers", s.size()))
+	}
+	if c.lacksSignerSources() {
+		report("signers", ErrNoSignerSource, "")
+	}
+	return ok
+}
@@ -0,0 +1,78 @@
+package clabot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+// signersDoc is the object form of a SIGNERS_URL response; a bare JSON array
+// of entries is accepted too.
+type signersDoc struct {
+	Logins []string `json:"logins"`
+	Emails []string `json:"emails"`
+}
+
+// maxSignersBody bounds how much of a SIGNERS_URL response is read.
+const maxSignersBody = 10 << 20
+
+// loadSignersFromURL fetches signers from an HTTP(S) endpoint serving JSON.
+func loadSignersFromURL(ctx context.Context, c Config, url string) (SignerSet, error) {
+	signers := NewSignerSet()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return signers, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.SignersAuthHeader != "" {
+		req.Header.Set("Authorization", c.SignersAuthHeader)
+	}
+
+	resp, err := externalClient(c).Do(req)
+	if err != nil {
+		return signers, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return signers, fmt.Errorf("signers endpoint returned %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSignersBody))
+	if err != nil {
+		return signers, err
+	}
+	signers, err = parseSignersJSON(data)
+	if err != nil {
+		return signers, err
+	}
+
+	signers.logSigners("Endpoint", url)
+	return signers, nil
+}
+
+// parseSignersJSON accepts either ["login", ...] or
+// {"logins": [...], "emails": [...]}.
+func parseSignersJSON(data []byte) (SignerSet, error) {
+	signers := NewSignerSet()
+
+	var entries []string
+	if err := json.Unmarshal(data, &entries); err != nil {
+		var doc signersDoc
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return signers, errors.New("expected a JSON array of signers or an object with logins and emails")
+		}
+		entries = append(doc.Logins, doc.Emails...)
+	}
+	for _, e := range entries {
+		signers.add(e, literalEntries)
+	}
+	if len(entries) == 0 {
+		log.Warn().Msg("Signers endpoint returned no signers")
+	}
+	return signers, nil
+}
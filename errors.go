@@ -0,0 +1,25 @@
+package clabot
+
+import "errors"
+
+// Signer source failures. Loaders wrap them with the underlying cause and
+// loadSigners adds the sheet URL or file path, so callers can tell them apart
+// with errors.Is.
+var (
+	// ErrSheetUnavailable is a transient failure to fetch a Google Sheet: a
+	// network error or timeout, a rate limit or a 5xx. It is worth retrying.
+	ErrSheetUnavailable = errors.New("google sheet unavailable")
+	// ErrSheetNotPublished means the sheet answered, but not with a CSV
+	// export, usually because it isn't published to the web.
+	ErrSheetNotPublished = errors.New("google sheet not published as CSV")
+	// ErrSignersFileNotFound is a signers file that is required but missing.
+	ErrSignersFileNotFound = errors.New("signers file not found")
+	// ErrNoSignerSource means CLA mode has nothing to load signers from.
+	ErrNoSignerSource = errors.New("no signer source configured: set SIGNERS_PATH, GOOGLE_SHEET_URL, SIGNERS_URL or SIGNERS_FILE_LOCAL, or ALLOW_EMPTY_SIGNERS=true")
+)
+
+// misconfigured reports whether err is a permanent signer source problem
+// that a maintainer has to fix, rather than one a rerun may get past.
+func misconfigured(err error) bool {
+	return errors.Is(err, ErrSheetNotPublished) || errors.Is(err, ErrSignersFileNotFound) || errors.Is(err, ErrNoSignerSource)
+}
@@ -0,0 +1,172 @@
+package clabot
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	apiCacheOff    = "off"
+	apiCacheMemory = "memory"
+	apiCacheDisk   = "disk"
+)
+
+// maxCachedBody keeps huge listings out of the API cache.
+const maxCachedBody = 1 << 20
+
+// etagTransport caches GET responses that carry an ETag and revalidates them
+// with If-None-Match. GitHub doesn't count a 304 against the rate limit, so
+// rechecks that read the same signers file and commits are nearly free.
+// Entries live in memory and, when dir is set, also on disk.
+type etagTransport struct {
+	base http.RoundTripper
+	dir  string
+
+	mu      sync.Mutex
+	entries map[string]etagEntry
+}
+
+type etagEntry struct {
+	ETag   string      `json:"etag"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// newETagTransport wraps base according to API_CACHE; with caching off it
+// returns base unchanged.
+func newETagTransport(base http.RoundTripper, c Config) http.RoundTripper {
+	t := &etagTransport{base: base, entries: make(map[string]etagEntry)}
+	switch c.APICache {
+	case apiCacheMemory:
+	case apiCacheDisk:
+		t.dir = c.APICachePath
+		if t.dir == "" {
+			dir, err := os.UserCacheDir()
+			if err != nil {
+				log.Warn().Err(err).Msg("No cache directory, API cache kept in memory")
+				break
+			}
+			t.dir = filepath.Join(dir, "clabot", "api")
+		}
+	default:
+		return base
+	}
+	return t
+}
+
+func (t *etagTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.base.RoundTrip(req)
+	}
+	key := req.URL.String() + " " + req.Header.Get("Accept")
+	cached, ok := t.get(key)
+	if ok {
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	if ok && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		log.Debug().Str("url", req.URL.String()).Msg("GitHub API cache hit")
+		return cachedResponse(req, resp, cached), nil
+	}
+
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" || resp.ContentLength > maxCachedBody {
+		return resp, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCachedBody+1))
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if len(body) <= maxCachedBody {
+		t.put(key, etagEntry{ETag: etag, Header: resp.Header.Clone(), Body: body})
+	}
+	return resp, nil
+}
+
+// cachedResponse turns a 304 back into the 200 it stands for, keeping the
+// fresh rate limit headers from the 304.
+func cachedResponse(req *http.Request, notModified *http.Response, e etagEntry) *http.Response {
+	header := e.Header.Clone()
+	for k, v := range notModified.Header {
+		header[k] = v
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         notModified.Proto,
+		ProtoMajor:    notModified.ProtoMajor,
+		ProtoMinor:    notModified.ProtoMinor,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+func (t *etagTransport) get(key string) (etagEntry, bool) {
+	t.mu.Lock()
+	e, ok := t.entries[key]
+	t.mu.Unlock()
+	if ok || t.dir == "" {
+		return e, ok
+	}
+
+	data, err := os.ReadFile(t.file(key))
+	if err != nil {
+		return e, false
+	}
+	if err := json.Unmarshal(data, &e); err != nil || e.ETag == "" {
+		return etagEntry{}, false
+	}
+	t.mu.Lock()
+	t.entries[key] = e
+	t.mu.Unlock()
+	return e, true
+}
+
+func (t *etagTransport) put(key string, e etagEntry) {
+	t.mu.Lock()
+	t.entries[key] = e
+	t.mu.Unlock()
+	if t.dir == "" {
+		return
+	}
+
+	data, err := json.Marshal(e)
+	if err == nil {
+		err = os.MkdirAll(t.dir, 0o700)
+	}
+	if err == nil {
+		p := t.file(key)
+		tmp := p + ".tmp"
+		if err = os.WriteFile(tmp, data, 0o600); err == nil {
+			err = os.Rename(tmp, p)
+		}
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("dir", t.dir).Msg("Failed to write API cache")
+	}
+}
+
+// file names the disk entry for key; URLs don't make safe file names.
+func (t *etagTransport) file(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(t.dir, hex.EncodeToString(sum[:])+".json")
+}
@@ -0,0 +1,120 @@
+package clabot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v58/github"
+)
+
+// isBot reports whether login is an automated account that never signs: one
+// listed in IgnoreAuthors or, with SkipBots, any "[bot]" login.
+func isBot(c Config, login string) bool {
+	if _, ok := c.IgnoreAuthors[login]; ok {
+		return true
+	}
+	return c.SkipBots && strings.HasSuffix(login, "[bot]")
+}
+
+// exemptDomain reports whether every commit email of the contributor is under
+// one of EXEMPT_DOMAINS. A contributor without emails is never exempt.
+func exemptDomain(c Config, ct *Contributor) bool {
+	if len(c.ExemptDomains) == 0 || len(ct.Emails) == 0 {
+		return false
+	}
+	for _, email := range ct.Emails {
+		_, domain, _ := strings.Cut(email, "@")
+		if !slices.Contains(c.ExemptDomains, domain) {
+			return false
+		}
+	}
+	return true
+}
+
+// defaultLookupConcurrency bounds the membership and team lookups a check
+// runs at once unless LOOKUP_CONCURRENCY says otherwise.
+const defaultLookupConcurrency = 4
+
+// orgExemptions decides whether a login is exempt from the CLA by way of
+// EXEMPT_ORG / EXEMPT_TEAMS membership. Lookups are cached for the run, and
+// it is safe for concurrent use.
+type orgExemptions struct {
+	gh    *Client
+	org   string
+	teams []string
+
+	mu    sync.Mutex
+	cache map[string]bool
+}
+
+func newOrgExemptions(gh *Client, c Config) *orgExemptions {
+	return &orgExemptions{
+		gh:    gh,
+		org:   c.ExemptOrg,
+		teams: c.ExemptTeams,
+		cache: make(map[string]bool),
+	}
+}
+
+func (o *orgExemptions) isExempt(ctx context.Context, login string) (bool, error) {
+	if o.org == "" || login == "" {
+		return false, nil
+	}
+	o.mu.Lock()
+	v, ok := o.cache[login]
+	o.mu.Unlock()
+	if ok {
+		return v, nil
+	}
+
+	v, err := o.lookup(ctx, login)
+	if err != nil {
+		return false, err
+	}
+	o.mu.Lock()
+	o.cache[login] = v
+	o.mu.Unlock()
+	return v, nil
+}
+
+func (o *orgExemptions) lookup(ctx context.Context, login string) (bool, error) {
+	if len(o.teams) == 0 {
+		member, _, err := o.gh.Organizations.IsMember(ctx, o.org, login)
+		return member, err
+	}
+
+	for _, slug := range o.teams {
+		m, _, err := o.gh.Teams.GetTeamMembershipBySlug(ctx, o.org, slug, login)
+		if isNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if m.GetState() == "active" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isInternalMaintainerPR reports whether the PR comes from a branch of the
+// base repository, not a fork, and its author can push there. The head repo
+// is nil when the fork has been deleted; that never counts as internal.
+func isInternalMaintainerPR(ctx context.Context, gh *Client, c Config, pr *github.PullRequest) (bool, error) {
+	head, base := pr.GetHead().GetRepo(), pr.GetBase().GetRepo()
+	if head == nil || base == nil || head.GetID() != base.GetID() {
+		return false, nil
+	}
+	return hasWriteAccess(ctx, gh, c, strings.ToLower(pr.GetUser().GetLogin()))
+}
+
+// isNotFound reports whether err is a GitHub 404.
+func isNotFound(err error) bool {
+	var ghErr *github.ErrorResponse
+	return errors.As(err, &ghErr) && ghErr.Response != nil && ghErr.Response.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,177 @@
+package clabot
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"sync"
+
+	"github.com/google/go-github/v58/github"
+)
+
+// fakeGitHub serves a single repository from memory and records what clabot
+// writes to it. Calls the fakes don't implement panic on the nil embedded
+// interface, so a test fails loudly when the code under test grows a new call.
+type fakeGitHub struct {
+	mu       sync.Mutex
+	commits  []*github.RepositoryCommit
+	prFiles  []*github.CommitFile
+	files    map[string]string // repository contents by path
+	comments []*github.IssueComment
+	statuses []*github.RepoStatus // newest first, like the API
+	botLogin string
+}
+
+func newFakeGitHub() *fakeGitHub {
+	return &fakeGitHub{files: make(map[string]string), botLogin: actionsBot}
+}
+
+// client returns a Client backed by f.
+func (f *fakeGitHub) client() *Client {
+	return &Client{
+		Issues:       fakeIssues{f: f},
+		PullRequests: fakePullRequests{f: f},
+		Repositories: fakeRepositories{f: f},
+		Users:        fakeUsers{f: f},
+	}
+}
+
+func notFound() (*github.Response, error) {
+	resp := &github.Response{Response: &http.Response{StatusCode: http.StatusNotFound}}
+	return resp, &github.ErrorResponse{Response: resp.Response, Message: "Not Found"}
+}
+
+func ok() *github.Response {
+	return &github.Response{Response: &http.Response{StatusCode: http.StatusOK}}
+}
+
+type fakeIssues struct {
+	issuesAPI
+	f *fakeGitHub
+}
+
+func (i fakeIssues) ListComments(ctx context.Context, owner, repo string, number int, opts *github.IssueListCommentsOptions) ([]*github.IssueComment, *github.Response, error) {
+	i.f.mu.Lock()
+	defer i.f.mu.Unlock()
+	return append([]*github.IssueComment(nil), i.f.comments...), ok(), nil
+}
+
+func (i fakeIssues) CreateComment(ctx context.Context, owner, repo string, number int, comment *github.IssueComment) (*github.IssueComment, *github.Response, error) {
+	i.f.mu.Lock()
+	defer i.f.mu.Unlock()
+	cm := &github.IssueComment{
+		ID:   github.Int64(int64(len(i.f.comments) + 1)),
+		Body: comment.Body,
+		User: &github.User{Login: github.String(i.f.botLogin)},
+	}
+	i.f.comments = append(i.f.comments, cm)
+	return cm, ok(), nil
+}
+
+func (i fakeIssues) EditComment(ctx context.Context, owner, repo string, commentID int64, comment *github.IssueComment) (*github.IssueComment, *github.Response, error) {
+	i.f.mu.Lock()
+	defer i.f.mu.Unlock()
+	for _, cm := range i.f.comments {
+		if cm.GetID() == commentID {
+			cm.Body = comment.Body
+			return cm, ok(), nil
+		}
+	}
+	resp, err := notFound()
+	return nil, resp, err
+}
+
+func (i fakeIssues) DeleteComment(ctx context.Context, owner, repo string, commentID int64) (*github.Response, error) {
+	i.f.mu.Lock()
+	defer i.f.mu.Unlock()
+	for n, cm := range i.f.comments {
+		if cm.GetID() == commentID {
+			i.f.comments = append(i.f.comments[:n], i.f.comments[n+1:]...)
+			return ok(), nil
+		}
+	}
+	return notFound()
+}
+
+type fakePullRequests struct {
+	pullRequestsAPI
+	f *fakeGitHub
+}
+
+func (p fakePullRequests) ListCommits(ctx context.Context, owner, repo string, number int, opts *github.ListOptions) ([]*github.RepositoryCommit, *github.Response, error) {
+	items, resp := page(p.f.commits, opts)
+	return items, resp, nil
+}
+
+func (p fakePullRequests) ListFiles(ctx context.Context, owner, repo string, number int, opts *github.ListOptions) ([]*github.CommitFile, *github.Response, error) {
+	items, resp := page(p.f.prFiles, opts)
+	return items, resp, nil
+}
+
+// page serves one page of all the way the API does, numbering pages from 1
+// and setting NextPage on every page but the last.
+func page[T any](all []T, opts *github.ListOptions) ([]T, *github.Response) {
+	per := opts.PerPage
+	if per == 0 {
+		per = 30
+	}
+	n := max(opts.Page, 1)
+	lo, hi := min((n-1)*per, len(all)), min(n*per, len(all))
+	resp := ok()
+	if hi < len(all) {
+		resp.NextPage = n + 1
+	}
+	return all[lo:hi], resp
+}
+
+type fakeRepositories struct {
+	repositoriesAPI
+	f *fakeGitHub
+}
+
+func (r fakeRepositories) GetContents(ctx context.Context, owner, repo, path string, opts *github.RepositoryContentGetOptions) (*github.RepositoryContent, []*github.RepositoryContent, *github.Response, error) {
+	body, found := r.f.files[path]
+	if !found {
+		resp, err := notFound()
+		return nil, nil, resp, err
+	}
+	return &github.RepositoryContent{
+		Type:     github.String("file"),
+		Path:     github.String(path),
+		Encoding: github.String("base64"),
+		Content:  github.String(base64.StdEncoding.EncodeToString([]byte(body))),
+	}, nil, ok(), nil
+}
+
+func (r fakeRepositories) ListStatuses(ctx context.Context, owner, repo, ref string, opts *github.ListOptions) ([]*github.RepoStatus, *github.Response, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	return append([]*github.RepoStatus(nil), r.f.statuses...), ok(), nil
+}
+
+func (r fakeRepositories) CreateStatus(ctx context.Context, owner, repo, ref string, status *github.RepoStatus) (*github.RepoStatus, *github.Response, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	r.f.statuses = append([]*github.RepoStatus{status}, r.f.statuses...)
+	return status, ok(), nil
+}
+
+type fakeUsers struct {
+	usersAPI
+	f *fakeGitHub
+}
+
+func (u fakeUsers) Get(ctx context.Context, user string) (*github.User, *github.Response, error) {
+	if user == "" {
+		user = u.f.botLogin
+	}
+	return &github.User{Login: github.String(user)}, ok(), nil
+}
+
+// commit returns a commit authored by login.
+func commit(login, email string) *github.RepositoryCommit {
+	return &github.RepositoryCommit{
+		Author: &github.User{Login: github.String(login)},
+		Commit: &github.Commit{Author: &github.CommitAuthor{Email: github.String(email)}},
+	}
+}
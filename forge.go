@@ -0,0 +1,61 @@
+package clabot
+
+import (
+	"context"
+
+	"github.com/google/go-github/v58/github"
+)
+
+// Values for FORGE.
+const (
+	forgeGitHub = "github"
+	forgeGitLab = "gitlab"
+)
+
+// forge is how the checks report back to the code host: a status on the
+// head commit and comments on the pull request. On GitLab these are commit
+// statuses and merge request notes, which are addressed through the merge
+// request, so the comment calls take its number too.
+type forge interface {
+	setStatus(ctx context.Context, sha, state, description string) error
+	createComment(ctx context.Context, number int, body string) error
+	editComment(ctx context.Context, number int, id int64, body string) error
+	deleteComment(ctx context.Context, number int, id int64) error
+}
+
+// newForge returns the forge for c.Forge, which LoadConfig has validated.
+func newForge(gh *Client, c Config) forge {
+	if gh.gitlab != nil {
+		return gitlabForge{g: gh.gitlab, c: c}
+	}
+	return githubForge{gh: gh, c: c}
+}
+
+type githubForge struct {
+	gh *Client
+	c  Config
+}
+
+func (f githubForge) setStatus(ctx context.Context, sha, state, description string) error {
+	_, _, err := f.gh.Repositories.CreateStatus(ctx, f.c.RepoOwner, f.c.RepoName, sha, &github.RepoStatus{
+		State:       github.String(state), // "success" | "failure"
+		Description: github.String(description),
+		Context:     github.String(f.c.StatusContext),
+	})
+	return err
+}
+
+func (f githubForge) createComment(ctx context.Context, number int, body string) error {
+	_, _, err := f.gh.Issues.CreateComment(ctx, f.c.RepoOwner, f.c.RepoName, number, &github.IssueComment{Body: github.String(body)})
+	return err
+}
+
+func (f githubForge) editComment(ctx context.Context, _ int, id int64, body string) error {
+	_, _, err := f.gh.Issues.EditComment(ctx, f.c.RepoOwner, f.c.RepoName, id, &github.IssueComment{Body: github.String(body)})
+	return err
+}
+
+func (f githubForge) deleteComment(ctx context.Context, _ int, id int64) error {
+	_, err := f.gh.Issues.DeleteComment(ctx, f.c.RepoOwner, f.c.RepoName, id)
+	return err
+}
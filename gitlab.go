@@ -0,0 +1,799 @@
+package clabot
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+)
+
+// defaultGitLabAPIURL is the REST endpoint used with FORGE=gitlab unless
+// GITLAB_API_URL (or CI_API_V4_URL in GitLab CI) points elsewhere.
+const defaultGitLabAPIURL = "https://gitlab.com/api/v4"
+
+// errGitLabUnsupported is returned by the API calls a GitLab project has no
+// counterpart for, such as check runs, org membership and user search.
+var errGitLabUnsupported = errors.New("not supported with FORGE=gitlab")
+
+// gitlabAPI is a small client for the GitLab REST API. It backs both the
+// *Client a GitLab check reads through and the forge it reports with.
+type gitlabAPI struct {
+	base  string
+	token string
+	hc    *http.Client
+}
+
+func newGitLabAPI(c Config) *gitlabAPI {
+	return &gitlabAPI{
+		base:  strings.TrimSuffix(c.APIURL, "/"),
+		token: c.Token,
+		hc:    externalClient(c),
+	}
+}
+
+// newGitLabClient returns a Client that serves the GitHub-shaped calls the
+// checks make from a GitLab project: merge requests stand in for pull
+// requests, notes for comments and repository files for contents.
+func newGitLabClient(c Config) *Client {
+	g := newGitLabAPI(c)
+	return &Client{
+		Checks:        gitlabUnsupported{},
+		Issues:        gitlabIssues{g: g},
+		Organizations: gitlabUnsupported{},
+		PullRequests:  gitlabMergeRequests{g: g},
+		RateLimit:     gitlabRateLimit{g: g},
+		Repositories:  gitlabRepositories{g: g},
+		Search:        gitlabUnsupported{},
+		Teams:         gitlabUnsupported{},
+		Users:         gitlabUsers{g: g},
+		gitlab:        g,
+	}
+}
+
+// gitlabProjectPath is the URL path of a project, which GitLab addresses by
+// its namespaced path with the slashes escaped.
+func gitlabProjectPath(owner, name string) string {
+	return "/projects/" + escapeGitLabPath(owner+"/"+name)
+}
+
+func escapeGitLabPath(p string) string {
+	return strings.ReplaceAll(url.PathEscape(p), "/", "%2F")
+}
+
+// do sends a request to path, relative to the API root and already escaped,
+// and decodes a JSON response into out when it isn't nil. Errors are
+// *github.ErrorResponse, so isNotFound and friends work unchanged.
+func (g *gitlabAPI) do(ctx context.Context, method, path string, query url.Values, body, out any) (*github.Response, error) {
+	u := g.base + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var rd io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		rd = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, rd)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("PRIVATE-TOKEN", g.token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	hr, err := g.hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer hr.Body.Close()
+	resp := &github.Response{Response: hr}
+	resp.NextPage, _ = strconv.Atoi(hr.Header.Get("X-Next-Page"))
+
+	if hr.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(hr.Body, 64<<10))
+		var e struct {
+			Message any    `json:"message"`
+			Error   string `json:"error"`
+		}
+		msg := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &e) == nil {
+			switch {
+			case e.Message != nil:
+				msg = fmt.Sprint(e.Message)
+			case e.Error != "":
+				msg = e.Error
+			}
+		}
+		return resp, &github.ErrorResponse{Response: hr, Message: msg}
+	}
+	if out != nil {
+		if err := json.NewDecoder(hr.Body).Decode(out); err != nil {
+			return resp, fmt.Errorf("decode %s: %w", path, err)
+		}
+	}
+	return resp, nil
+}
+
+// pageQuery carries the paging of opts over to GitLab, which uses the same
+// page and per_page parameters.
+func pageQuery(opts *github.ListOptions) url.Values {
+	q := url.Values{}
+	if opts != nil && opts.PerPage > 0 {
+		q.Set("per_page", strconv.Itoa(opts.PerPage))
+	}
+	if opts != nil && opts.Page > 0 {
+		q.Set("page", strconv.Itoa(opts.Page))
+	}
+	return q
+}
+
+type gitlabUser struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+func (u gitlabUser) user() *github.User {
+	return &github.User{ID: github.Int64(u.ID), Login: github.String(u.Username)}
+}
+
+type gitlabMergeRequest struct {
+	IID             int        `json:"iid"`
+	Title           string     `json:"title"`
+	Description     string     `json:"description"`
+	State           string     `json:"state"` // opened, closed, merged or locked
+	Draft           bool       `json:"draft"`
+	SHA             string     `json:"sha"`
+	MergeCommitSHA  string     `json:"merge_commit_sha"`
+	SourceBranch    string     `json:"source_branch"`
+	TargetBranch    string     `json:"target_branch"`
+	SourceProjectID int64      `json:"source_project_id"`
+	TargetProjectID int64      `json:"target_project_id"`
+	Author          gitlabUser `json:"author"`
+	CreatedAt       time.Time  `json:"created_at"`
+	DiffRefs        struct {
+		BaseSHA string `json:"base_sha"`
+	} `json:"diff_refs"`
+}
+
+// pullRequest maps the merge request onto the fields the checks read. The
+// source and target projects only carry their IDs, which is all the
+// internal-PR check compares.
+func (m gitlabMergeRequest) pullRequest() *github.PullRequest {
+	state := "open"
+	if m.State != "opened" {
+		state = "closed"
+	}
+	return &github.PullRequest{
+		Number:         github.Int(m.IID),
+		Title:          github.String(m.Title),
+		Body:           github.String(m.Description),
+		State:          github.String(state),
+		Draft:          github.Bool(m.Draft),
+		Merged:         github.Bool(m.State == "merged"),
+		MergeCommitSHA: github.String(m.MergeCommitSHA),
+		User:           m.Author.user(),
+		CreatedAt:      &github.Timestamp{Time: m.CreatedAt},
+		Head: &github.PullRequestBranch{
+			SHA:  github.String(m.SHA),
+			Ref:  github.String(m.SourceBranch),
+			Repo: &github.Repository{ID: github.Int64(m.SourceProjectID)},
+		},
+		Base: &github.PullRequestBranch{
+			SHA:  github.String(m.DiffRefs.BaseSHA),
+			Ref:  github.String(m.TargetBranch),
+			Repo: &github.Repository{ID: github.Int64(m.TargetProjectID)},
+		},
+	}
+}
+
+type gitlabMergeRequests struct{ g *gitlabAPI }
+
+func (s gitlabMergeRequests) Get(ctx context.Context, owner, repo string, number int) (*github.PullRequest, *github.Response, error) {
+	var mr gitlabMergeRequest
+	resp, err := s.g.do(ctx, http.MethodGet, fmt.Sprintf("%s/merge_requests/%d", gitlabProjectPath(owner, repo), number), nil, nil, &mr)
+	if err != nil {
+		return nil, resp, err
+	}
+	return mr.pullRequest(), resp, nil
+}
+
+func (s gitlabMergeRequests) List(ctx context.Context, owner, repo string, opts *github.PullRequestListOptions) ([]*github.PullRequest, *github.Response, error) {
+	var lo *github.ListOptions
+	q := url.Values{}
+	if opts != nil {
+		lo = &opts.ListOptions
+		switch opts.State {
+		case "open":
+			q.Set("state", "opened")
+		case "closed":
+			q.Set("state", "closed")
+		}
+	}
+	for k, v := range pageQuery(lo) {
+		q[k] = v
+	}
+	var mrs []gitlabMergeRequest
+	resp, err := s.g.do(ctx, http.MethodGet, gitlabProjectPath(owner, repo)+"/merge_requests", q, nil, &mrs)
+	if err != nil {
+		return nil, resp, err
+	}
+	prs := make([]*github.PullRequest, len(mrs))
+	for i, mr := range mrs {
+		prs[i] = mr.pullRequest()
+	}
+	return prs, resp, nil
+}
+
+type gitlabCommit struct {
+	ID             string   `json:"id"`
+	Message        string   `json:"message"`
+	AuthorName     string   `json:"author_name"`
+	AuthorEmail    string   `json:"author_email"`
+	CommitterName  string   `json:"committer_name"`
+	CommitterEmail string   `json:"committer_email"`
+	ParentIDs      []string `json:"parent_ids"`
+}
+
+// ListCommits returns the merge request's commits. GitLab doesn't link them
+// to accounts, so contributors other than the author are known only by
+// email.
+func (s gitlabMergeRequests) ListCommits(ctx context.Context, owner, repo string, number int, opts *github.ListOptions) ([]*github.RepositoryCommit, *github.Response, error) {
+	var commits []gitlabCommit
+	resp, err := s.g.do(ctx, http.MethodGet, fmt.Sprintf("%s/merge_requests/%d/commits", gitlabProjectPath(owner, repo), number), pageQuery(opts), nil, &commits)
+	if err != nil {
+		return nil, resp, err
+	}
+	out := make([]*github.RepositoryCommit, len(commits))
+	for i, gc := range commits {
+		rc := &github.RepositoryCommit{
+			SHA: github.String(gc.ID),
+			Commit: &github.Commit{
+				Message:   github.String(gc.Message),
+				Author:    &github.CommitAuthor{Name: github.String(gc.AuthorName), Email: github.String(gc.AuthorEmail)},
+				Committer: &github.CommitAuthor{Name: github.String(gc.CommitterName), Email: github.String(gc.CommitterEmail)},
+			},
+		}
+		for _, p := range gc.ParentIDs {
+			rc.Parents = append(rc.Parents, &github.Commit{SHA: github.String(p)})
+		}
+		out[i] = rc
+	}
+	return out, resp, nil
+}
+
+func (s gitlabMergeRequests) ListFiles(ctx context.Context, owner, repo string, number int, opts *github.ListOptions) ([]*github.CommitFile, *github.Response, error) {
+	var diffs []struct {
+		OldPath     string `json:"old_path"`
+		NewPath     string `json:"new_path"`
+		NewFile     bool   `json:"new_file"`
+		RenamedFile bool   `json:"renamed_file"`
+		DeletedFile bool   `json:"deleted_file"`
+	}
+	resp, err := s.g.do(ctx, http.MethodGet, fmt.Sprintf("%s/merge_requests/%d/diffs", gitlabProjectPath(owner, repo), number), pageQuery(opts), nil, &diffs)
+	if err != nil {
+		return nil, resp, err
+	}
+	files := make([]*github.CommitFile, len(diffs))
+	for i, d := range diffs {
+		f := &github.CommitFile{Filename: github.String(d.NewPath), Status: github.String("modified")}
+		switch {
+		case d.NewFile:
+			f.Status = github.String("added")
+		case d.DeletedFile:
+			f.Status = github.String("removed")
+		case d.RenamedFile:
+			f.Status = github.String("renamed")
+			f.PreviousFilename = github.String(d.OldPath)
+		}
+		files[i] = f
+	}
+	return files, resp, nil
+}
+
+type gitlabNote struct {
+	ID        int64      `json:"id"`
+	Body      string     `json:"body"`
+	System    bool       `json:"system"`
+	Author    gitlabUser `json:"author"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// gitlabIssues serves merge request notes and labels. Notes are written
+// through the forge, which knows the merge request they belong to.
+type gitlabIssues struct{ g *gitlabAPI }
+
+func (s gitlabIssues) ListComments(ctx context.Context, owner, repo string, number int, opts *github.IssueListCommentsOptions) ([]*github.IssueComment, *github.Response, error) {
+	var lo *github.ListOptions
+	if opts != nil {
+		lo = &opts.ListOptions
+	}
+	q := pageQuery(lo)
+	q.Set("sort", "asc")
+	q.Set("order_by", "created_at")
+	var notes []gitlabNote
+	resp, err := s.g.do(ctx, http.MethodGet, fmt.Sprintf("%s/merge_requests/%d/notes", gitlabProjectPath(owner, repo), number), q, nil, &notes)
+	if err != nil {
+		return nil, resp, err
+	}
+	var comments []*github.IssueComment
+	for _, n := range notes {
+		if n.System {
+			continue // "added 1 commit" and the like
+		}
+		comments = append(comments, &github.IssueComment{
+			ID:        github.Int64(n.ID),
+			Body:      github.String(n.Body),
+			User:      n.Author.user(),
+			CreatedAt: &github.Timestamp{Time: n.CreatedAt},
+			UpdatedAt: &github.Timestamp{Time: n.UpdatedAt},
+		})
+	}
+	return comments, resp, nil
+}
+
+func (s gitlabIssues) AddLabelsToIssue(ctx context.Context, owner, repo string, number int, labels []string) ([]*github.Label, *github.Response, error) {
+	resp, err := s.g.do(ctx, http.MethodPut, fmt.Sprintf("%s/merge_requests/%d", gitlabProjectPath(owner, repo), number), nil,
+		map[string]string{"add_labels": strings.Join(labels, ",")}, nil)
+	return nil, resp, err
+}
+
+func (s gitlabIssues) RemoveLabelForIssue(ctx context.Context, owner, repo string, number int, label string) (*github.Response, error) {
+	return s.g.do(ctx, http.MethodPut, fmt.Sprintf("%s/merge_requests/%d", gitlabProjectPath(owner, repo), number), nil,
+		map[string]string{"remove_labels": label}, nil)
+}
+
+func (gitlabIssues) CreateComment(context.Context, string, string, int, *github.IssueComment) (*github.IssueComment, *github.Response, error) {
+	return nil, nil, fmt.Errorf("create comment: %w", errGitLabUnsupported)
+}
+
+func (gitlabIssues) EditComment(context.Context, string, string, int64, *github.IssueComment) (*github.IssueComment, *github.Response, error) {
+	return nil, nil, fmt.Errorf("edit comment: %w", errGitLabUnsupported)
+}
+
+func (gitlabIssues) DeleteComment(context.Context, string, string, int64) (*github.Response, error) {
+	return nil, fmt.Errorf("delete comment: %w", errGitLabUnsupported)
+}
+
+type gitlabRepositories struct{ g *gitlabAPI }
+
+func (s gitlabRepositories) Get(ctx context.Context, owner, repo string) (*github.Repository, *github.Response, error) {
+	var p struct {
+		ID                int64  `json:"id"`
+		Path              string `json:"path"`
+		PathWithNamespace string `json:"path_with_namespace"`
+		DefaultBranch     string `json:"default_branch"`
+		Visibility        string `json:"visibility"`
+	}
+	resp, err := s.g.do(ctx, http.MethodGet, gitlabProjectPath(owner, repo), nil, nil, &p)
+	if err != nil {
+		return nil, resp, err
+	}
+	return &github.Repository{
+		ID:            github.Int64(p.ID),
+		Name:          github.String(p.Path),
+		FullName:      github.String(p.PathWithNamespace),
+		DefaultBranch: github.String(p.DefaultBranch),
+		Private:       github.Bool(p.Visibility != "public"),
+	}, resp, nil
+}
+
+// ListStatuses returns the latest commit status of each name on ref, with
+// GitLab's states mapped back to GitHub's.
+func (s gitlabRepositories) ListStatuses(ctx context.Context, owner, repo, ref string, opts *github.ListOptions) ([]*github.RepoStatus, *github.Response, error) {
+	var statuses []struct {
+		Name        string `json:"name"`
+		Status      string `json:"status"`
+		Description string `json:"description"`
+	}
+	resp, err := s.g.do(ctx, http.MethodGet, gitlabProjectPath(owner, repo)+"/repository/commits/"+escapeGitLabPath(ref)+"/statuses", pageQuery(opts), nil, &statuses)
+	if err != nil {
+		return nil, resp, err
+	}
+	out := make([]*github.RepoStatus, len(statuses))
+	for i, st := range statuses {
+		state := "pending"
+		switch st.Status {
+		case "success":
+			state = "success"
+		case "failed":
+			state = "failure"
+		case "canceled", "skipped":
+			state = "error"
+		}
+		out[i] = &github.RepoStatus{Context: github.String(st.Name), State: github.String(state), Description: github.String(st.Description)}
+	}
+	return out, resp, nil
+}
+
+func (s gitlabRepositories) GetCommitSHA1(ctx context.Context, owner, repo, ref, lastSHA string) (string, *github.Response, error) {
+	var c struct {
+		ID string `json:"id"`
+	}
+	resp, err := s.g.do(ctx, http.MethodGet, gitlabProjectPath(owner, repo)+"/repository/commits/"+escapeGitLabPath(ref), nil, nil, &c)
+	return c.ID, resp, err
+}
+
+// GetContents returns a file, or the entries of a directory when path isn't
+// a file. An empty ref reads the default branch.
+func (s gitlabRepositories) GetContents(ctx context.Context, owner, repo, path string, opts *github.RepositoryContentGetOptions) (*github.RepositoryContent, []*github.RepositoryContent, *github.Response, error) {
+	ref := "HEAD"
+	if opts != nil && opts.Ref != "" {
+		ref = opts.Ref
+	}
+	project := gitlabProjectPath(owner, repo)
+	var fileErr error
+	if path != "" {
+		var f struct {
+			FilePath string `json:"file_path"`
+			Encoding string `json:"encoding"`
+			Content  string `json:"content"`
+			BlobID   string `json:"blob_id"`
+		}
+		resp, err := s.g.do(ctx, http.MethodGet, project+"/repository/files/"+escapeGitLabPath(path), url.Values{"ref": {ref}}, nil, &f)
+		if err == nil {
+			return &github.RepositoryContent{
+				Type:     github.String("file"),
+				Path:     github.String(f.FilePath),
+				Encoding: github.String(f.Encoding),
+				Content:  github.String(f.Content),
+				SHA:      github.String(f.BlobID),
+			}, nil, resp, nil
+		}
+		if !isNotFound(err) {
+			return nil, nil, resp, err
+		}
+		fileErr = err
+	}
+
+	q := url.Values{"ref": {ref}, "per_page": {"100"}}
+	if path != "" {
+		q.Set("path", path)
+	}
+	var tree []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+		Type string `json:"type"` // blob or tree
+		Path string `json:"path"`
+	}
+	resp, err := s.g.do(ctx, http.MethodGet, project+"/repository/tree", q, nil, &tree)
+	if err != nil || len(tree) == 0 && fileErr != nil {
+		if fileErr != nil {
+			err = fileErr
+		}
+		return nil, nil, resp, err
+	}
+	entries := make([]*github.RepositoryContent, len(tree))
+	for i, e := range tree {
+		typ := "file"
+		if e.Type == "tree" {
+			typ = "dir"
+		}
+		entries[i] = &github.RepositoryContent{Type: github.String(typ), Name: github.String(e.Name), Path: github.String(e.Path), SHA: github.String(e.ID)}
+	}
+	return nil, entries, resp, nil
+}
+
+// GetPermissionLevel maps the user's project access level, inherited ones
+// included, to GitHub's permission names.
+func (s gitlabRepositories) GetPermissionLevel(ctx context.Context, owner, repo, user string) (*github.RepositoryPermissionLevel, *github.Response, error) {
+	var members []struct {
+		Username    string `json:"username"`
+		AccessLevel int    `json:"access_level"`
+	}
+	resp, err := s.g.do(ctx, http.MethodGet, gitlabProjectPath(owner, repo)+"/members/all", url.Values{"query": {user}}, nil, &members)
+	if err != nil {
+		return nil, resp, err
+	}
+	perm := "none"
+	for _, m := range members {
+		if !strings.EqualFold(m.Username, user) {
+			continue
+		}
+		switch {
+		case m.AccessLevel >= 50: // owner
+			perm = "admin"
+		case m.AccessLevel >= 40: // maintainer
+			perm = "maintain"
+		case m.AccessLevel >= 30: // developer
+			perm = "write"
+		case m.AccessLevel >= 10:
+			perm = "read"
+		}
+	}
+	return &github.RepositoryPermissionLevel{Permission: github.String(perm)}, resp, nil
+}
+
+func (gitlabRepositories) CreateStatus(context.Context, string, string, string, *github.RepoStatus) (*github.RepoStatus, *github.Response, error) {
+	return nil, nil, fmt.Errorf("create status: %w", errGitLabUnsupported)
+}
+
+func (s gitlabRepositories) CreateFile(ctx context.Context, owner, repo, path string, opts *github.RepositoryContentFileOptions) (*github.RepositoryContentResponse, *github.Response, error) {
+	return s.writeFile(ctx, http.MethodPost, owner, repo, path, opts)
+}
+
+func (s gitlabRepositories) UpdateFile(ctx context.Context, owner, repo, path string, opts *github.RepositoryContentFileOptions) (*github.RepositoryContentResponse, *github.Response, error) {
+	return s.writeFile(ctx, http.MethodPut, owner, repo, path, opts)
+}
+
+// writeFile commits opts.Content to path. GitLab needs the branch spelled
+// out, so an unset one is looked up as the project's default branch. The
+// file's SHA isn't passed on: GitLab guards updates by commit, not blob.
+func (s gitlabRepositories) writeFile(ctx context.Context, method, owner, repo, path string, opts *github.RepositoryContentFileOptions) (*github.RepositoryContentResponse, *github.Response, error) {
+	branch := opts.GetBranch()
+	if branch == "" {
+		p, resp, err := s.Get(ctx, owner, repo)
+		if err != nil {
+			return nil, resp, err
+		}
+		branch = p.GetDefaultBranch()
+	}
+	resp, err := s.g.do(ctx, method, gitlabProjectPath(owner, repo)+"/repository/files/"+escapeGitLabPath(path), nil, map[string]string{
+		"branch":         branch,
+		"commit_message": opts.GetMessage(),
+		"encoding":       "base64",
+		"content":        base64.StdEncoding.EncodeToString(opts.Content),
+	}, nil)
+	if err != nil {
+		return nil, resp, err
+	}
+	return &github.RepositoryContentResponse{}, resp, nil
+}
+
+type gitlabUsers struct{ g *gitlabAPI }
+
+// Get returns the token's own user for an empty login.
+func (s gitlabUsers) Get(ctx context.Context, login string) (*github.User, *github.Response, error) {
+	if login == "" {
+		var u gitlabUser
+		resp, err := s.g.do(ctx, http.MethodGet, "/user", nil, nil, &u)
+		if err != nil {
+			return nil, resp, err
+		}
+		return u.user(), resp, nil
+	}
+	var users []gitlabUser
+	resp, err := s.g.do(ctx, http.MethodGet, "/users", url.Values{"username": {login}}, nil, &users)
+	if err != nil {
+		return nil, resp, err
+	}
+	if len(users) == 0 {
+		return nil, resp, &github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusNotFound}, Message: login + " not found"}
+	}
+	return users[0].user(), resp, nil
+}
+
+// gitlabRateLimit only proves the API answers; GitLab reports no rate limit
+// of its own to read.
+type gitlabRateLimit struct{ g *gitlabAPI }
+
+func (s gitlabRateLimit) Get(ctx context.Context) (*github.RateLimits, *github.Response, error) {
+	resp, err := s.g.do(ctx, http.MethodGet, "/version", nil, nil, nil)
+	if err != nil {
+		return nil, resp, err
+	}
+	return &github.RateLimits{}, resp, nil
+}
+
+// gitlabUnsupported stands in for the GitHub services GitLab has no
+// counterpart for. LoadConfig rejects the settings that would need them.
+type gitlabUnsupported struct{}
+
+func (gitlabUnsupported) CreateCheckRun(context.Context, string, string, github.CreateCheckRunOptions) (*github.CheckRun, *github.Response, error) {
+	return nil, nil, fmt.Errorf("check runs: %w", errGitLabUnsupported)
+}
+
+func (gitlabUnsupported) ListCheckRunsForRef(context.Context, string, string, string, *github.ListCheckRunsOptions) (*github.ListCheckRunsResults, *github.Response, error) {
+	return nil, nil, fmt.Errorf("check runs: %w", errGitLabUnsupported)
+}
+
+func (gitlabUnsupported) UpdateCheckRun(context.Context, string, string, int64, github.UpdateCheckRunOptions) (*github.CheckRun, *github.Response, error) {
+	return nil, nil, fmt.Errorf("check runs: %w", errGitLabUnsupported)
+}
+
+func (gitlabUnsupported) IsMember(context.Context, string, string) (bool, *github.Response, error) {
+	return false, nil, fmt.Errorf("org membership: %w", errGitLabUnsupported)
+}
+
+func (gitlabUnsupported) Users(context.Context, string, *github.SearchOptions) (*github.UsersSearchResult, *github.Response, error) {
+	return nil, nil, fmt.Errorf("user search: %w", errGitLabUnsupported)
+}
+
+func (gitlabUnsupported) ListTeamMembersBySlug(context.Context, string, string, *github.TeamListTeamMembersOptions) ([]*github.User, *github.Response, error) {
+	return nil, nil, fmt.Errorf("teams: %w", errGitLabUnsupported)
+}
+
+func (gitlabUnsupported) GetTeamMembershipBySlug(context.Context, string, string, string) (*github.Membership, *github.Response, error) {
+	return nil, nil, fmt.Errorf("teams: %w", errGitLabUnsupported)
+}
+
+// gitlabForge reports as commit statuses and merge request notes.
+type gitlabForge struct {
+	g *gitlabAPI
+	c Config
+}
+
+func (f gitlabForge) project() string {
+	return gitlabProjectPath(f.c.RepoOwner, f.c.RepoName)
+}
+
+func (f gitlabForge) setStatus(ctx context.Context, sha, state, description string) error {
+	glState := "failed" // GitLab has no separate "error"
+	switch state {
+	case "success", "pending":
+		glState = state
+	}
+	_, err := f.g.do(ctx, http.MethodPost, f.project()+"/statuses/"+escapeGitLabPath(sha), nil, map[string]string{
+		"state":       glState,
+		"name":        f.c.StatusContext,
+		"description": description,
+	}, nil)
+	var ghErr *github.ErrorResponse
+	if errors.As(err, &ghErr) && strings.Contains(ghErr.Message, "Cannot transition status") {
+		// The status is already in that state; GitLab refuses to repeat it.
+		return nil
+	}
+	return err
+}
+
+func (f gitlabForge) createComment(ctx context.Context, number int, body string) error {
+	_, err := f.g.do(ctx, http.MethodPost, fmt.Sprintf("%s/merge_requests/%d/notes", f.project(), number), nil, map[string]string{"body": body}, nil)
+	return err
+}
+
+func (f gitlabForge) editComment(ctx context.Context, number int, id int64, body string) error {
+	_, err := f.g.do(ctx, http.MethodPut, fmt.Sprintf("%s/merge_requests/%d/notes/%d", f.project(), number, id), nil, map[string]string{"body": body}, nil)
+	return err
+}
+
+func (f gitlabForge) deleteComment(ctx context.Context, number int, id int64) error {
+	_, err := f.g.do(ctx, http.MethodDelete, fmt.Sprintf("%s/merge_requests/%d/notes/%d", f.project(), number, id), nil, nil, nil)
+	return err
+}
+
+// GitLab webhook event names, as sent in the X-Gitlab-Event header.
+const (
+	gitlabMergeRequestHook = "Merge Request Hook"
+	gitlabNoteHook         = "Note Hook"
+)
+
+type gitlabProject struct {
+	ID                int64  `json:"id"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	DefaultBranch     string `json:"default_branch"`
+}
+
+// repository splits the namespaced path into the owner (the group, which
+// may be nested) and the project name the checks address it by.
+func (p gitlabProject) repository() *github.Repository {
+	owner, name := "", p.PathWithNamespace
+	if i := strings.LastIndex(p.PathWithNamespace, "/"); i >= 0 {
+		owner, name = p.PathWithNamespace[:i], p.PathWithNamespace[i+1:]
+	}
+	return &github.Repository{
+		ID:            github.Int64(p.ID),
+		Name:          github.String(name),
+		FullName:      github.String(p.PathWithNamespace),
+		Owner:         &github.User{Login: github.String(owner)},
+		DefaultBranch: github.String(p.DefaultBranch),
+	}
+}
+
+type gitlabMergeRequestEvent struct {
+	User             gitlabUser    `json:"user"`
+	Project          gitlabProject `json:"project"`
+	ObjectAttributes struct {
+		IID    int    `json:"iid"`
+		Action string `json:"action"`
+		OldRev string `json:"oldrev"`
+	} `json:"object_attributes"`
+	Changes struct {
+		Draft *struct {
+			Previous bool `json:"previous"`
+			Current  bool `json:"current"`
+		} `json:"draft"`
+	} `json:"changes"`
+}
+
+// action names the merge request event after its pull_request counterpart,
+// so PR_ACTIONS and SKIP_DRAFTS apply to both forges alike.
+func (e gitlabMergeRequestEvent) action() string {
+	switch a := e.ObjectAttributes.Action; a {
+	case "open":
+		return "opened"
+	case "reopen":
+		return "reopened"
+	case "close", "merge":
+		return "closed"
+	case "update":
+		switch d := e.Changes.Draft; {
+		case e.ObjectAttributes.OldRev != "":
+			return "synchronize" // new commits were pushed
+		case d != nil && d.Previous && !d.Current:
+			return "ready_for_review"
+		case d != nil && !d.Previous && d.Current:
+			return "converted_to_draft"
+		}
+		return "edited"
+	default:
+		return a
+	}
+}
+
+type gitlabNoteEvent struct {
+	User             gitlabUser    `json:"user"`
+	Project          gitlabProject `json:"project"`
+	ObjectAttributes struct {
+		ID           int64  `json:"id"`
+		Note         string `json:"note"`
+		NoteableType string `json:"noteable_type"`
+		Action       string `json:"action"`
+	} `json:"object_attributes"`
+	MergeRequest *struct {
+		IID int `json:"iid"`
+	} `json:"merge_request"`
+}
+
+// parseGitLabWebHook turns a merge request or note hook into the event the
+// GitHub handlers take. The merge request event only carries its number: the
+// hook names the author by ID, so Dispatch reads the merge request itself.
+// Other events, and notes on anything but a merge request, return nil.
+func parseGitLabWebHook(name string, payload []byte) (any, error) {
+	switch name {
+	case gitlabMergeRequestHook:
+		var ev gitlabMergeRequestEvent
+		if err := json.Unmarshal(payload, &ev); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", name, err)
+		}
+		iid := ev.ObjectAttributes.IID
+		return &github.PullRequestEvent{
+			Action:      github.String(ev.action()),
+			Number:      github.Int(iid),
+			PullRequest: &github.PullRequest{Number: github.Int(iid)},
+			Repo:        ev.Project.repository(),
+			Sender:      ev.User.user(),
+		}, nil
+	case gitlabNoteHook:
+		var ev gitlabNoteEvent
+		if err := json.Unmarshal(payload, &ev); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", name, err)
+		}
+		if ev.ObjectAttributes.NoteableType != "MergeRequest" || ev.MergeRequest == nil {
+			return nil, nil
+		}
+		action := "created"
+		if ev.ObjectAttributes.Action == "update" {
+			action = "edited"
+		}
+		return &github.IssueCommentEvent{
+			Action: github.String(action),
+			Issue: &github.Issue{
+				Number:           github.Int(ev.MergeRequest.IID),
+				PullRequestLinks: &github.PullRequestLinks{},
+			},
+			Comment: &github.IssueComment{
+				ID:   github.Int64(ev.ObjectAttributes.ID),
+				Body: github.String(ev.ObjectAttributes.Note),
+				User: ev.User.user(),
+			},
+			Repo:   ev.Project.repository(),
+			Sender: ev.User.user(),
+		}, nil
+	}
+	return nil, nil
+}
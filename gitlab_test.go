@@ -0,0 +1,290 @@
+package clabot
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-github/v58/github"
+)
+
+// fakeGitLab serves merge request 7 of octo-org/octo-repo over the GitLab
+// REST API and records the statuses and notes clabot writes. Routes it
+// doesn't know fail the test.
+type fakeGitLab struct {
+	t        *testing.T
+	mu       sync.Mutex
+	files    map[string]string // repository contents by path
+	commits  []map[string]any
+	notes    []map[string]any
+	statuses []map[string]string // in the order posted
+}
+
+const gitlabTestProject = "/api/v4/projects/octo-org%2Focto-repo"
+
+func newFakeGitLab(t *testing.T) (*fakeGitLab, *httptest.Server) {
+	f := &fakeGitLab{t: t, files: make(map[string]string)}
+	srv := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeGitLab) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if got := r.Header.Get("PRIVATE-TOKEN"); got != "glpat-test" {
+		f.t.Errorf("%s %s: PRIVATE-TOKEN = %q", r.Method, r.URL, got)
+	}
+	var body map[string]string
+	if r.Body != nil {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}
+	path := strings.TrimPrefix(r.URL.EscapedPath(), gitlabTestProject)
+
+	reply := func(v any) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	}
+	switch route := r.Method + " " + path; {
+	case route == "GET /api/v4/user":
+		reply(map[string]any{"id": 99, "username": "clabot"})
+	case route == "GET /merge_requests/7":
+		reply(map[string]any{
+			"iid": 7, "state": "opened", "sha": "abc123",
+			"source_branch": "feature", "target_branch": "main",
+			"source_project_id": 43, "target_project_id": 42,
+			"author":    map[string]any{"id": 1, "username": "octocat"},
+			"diff_refs": map[string]any{"base_sha": "base000"},
+		})
+	case route == "GET /merge_requests/7/commits":
+		// One commit per page, to exercise X-Next-Page.
+		n, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		n = max(n, 1)
+		if n < len(f.commits) {
+			w.Header().Set("X-Next-Page", strconv.Itoa(n+1))
+		}
+		reply(f.commits[n-1 : n])
+	case route == "GET /merge_requests/7/notes":
+		reply(f.notes)
+	case route == "POST /merge_requests/7/notes":
+		f.notes = append(f.notes, map[string]any{
+			"id": len(f.notes) + 1, "body": body["body"],
+			"author": map[string]any{"id": 99, "username": "clabot"},
+		})
+		reply(f.notes[len(f.notes)-1])
+	case strings.HasPrefix(route, "PUT /merge_requests/7/notes/"):
+		id, _ := strconv.Atoi(strings.TrimPrefix(route, "PUT /merge_requests/7/notes/"))
+		f.notes[id-1]["body"] = body["body"]
+		reply(f.notes[id-1])
+	case route == "POST /statuses/abc123":
+		f.statuses = append(f.statuses, body)
+		reply(body)
+	case route == "GET /repository/commits/abc123/statuses":
+		var out []map[string]string
+		for _, st := range f.statuses {
+			out = append([]map[string]string{{"name": st["name"], "status": st["state"], "description": st["description"]}}, out...)
+		}
+		reply(out)
+	case strings.HasPrefix(route, "GET /repository/files/"):
+		name := strings.ReplaceAll(strings.TrimPrefix(path, "/repository/files/"), "%2F", "/")
+		content, ok := f.files[name]
+		if !ok {
+			http.Error(w, `{"message":"404 File Not Found"}`, http.StatusNotFound)
+			return
+		}
+		reply(map[string]any{
+			"file_path": name, "encoding": "base64", "blob_id": "blob" + strconv.Itoa(len(content)),
+			"content": base64.StdEncoding.EncodeToString([]byte(content)),
+		})
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		http.Error(w, `{"message":"404 Not Found"}`, http.StatusNotFound)
+	}
+}
+
+func gitlabTestConfig(t *testing.T, srv *httptest.Server) Config {
+	t.Helper()
+	t.Setenv("FORGE", "gitlab")
+	t.Setenv("GITLAB_TOKEN", "glpat-test")
+	t.Setenv("GITLAB_API_URL", srv.URL+"/api/v4")
+	t.Setenv("CLA_MATCH_EMAIL", "true")
+	c := testConfig(t)
+	c.SignersCacheTTL = 0
+	return c
+}
+
+func gitlabCommitJSON(name, email string) map[string]any {
+	return map[string]any{"id": "c-" + name, "author_name": name, "author_email": email, "message": "change"}
+}
+
+func TestGitLabDispatchMergeRequest(t *testing.T) {
+	f, srv := newFakeGitLab(t)
+	f.files[".github/signers.txt"] = "octocat\noctocat@example.com\n"
+	f.commits = []map[string]any{gitlabCommitJSON("octocat", "octocat@example.com"), gitlabCommitJSON("hubot", "hubot@example.com")}
+	c := gitlabTestConfig(t, srv)
+	gh, err := NewClient(c)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	ev, err := ParseForgeEvent(c.Forge, gitlabMergeRequestHook, "testdata/merge_request_hook.json")
+	if err != nil {
+		t.Fatalf("ParseForgeEvent: %v", err)
+	}
+	res, err := Dispatch(context.Background(), gh, c, ev)
+	if err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	if res.State != ResultUnsigned {
+		t.Fatalf("state = %v, want unsigned", res.State)
+	}
+	if n := len(f.statuses); n != 2 || f.statuses[0]["state"] != "pending" || f.statuses[1]["state"] != "failed" || f.statuses[1]["name"] != "CLA check" {
+		t.Fatalf("statuses = %v, want pending then failed on \"CLA check\"", f.statuses)
+	}
+	if len(f.notes) != 1 || !strings.Contains(f.notes[0]["body"].(string), commentMarker) {
+		t.Fatalf("notes = %v, want one clabot note", f.notes)
+	}
+
+	f.files[".github/signers.txt"] += "hubot@example.com\n"
+	c.CommentOnSuccess = true
+	if res, err = Dispatch(context.Background(), gh, c, ev); err != nil {
+		t.Fatalf("second Dispatch: %v", err)
+	}
+	if res.State != ResultSigned {
+		t.Errorf("state after signing = %v, want signed", res.State)
+	}
+	if got := f.statuses[len(f.statuses)-1]["state"]; got != "success" {
+		t.Errorf("last status = %s, want success", got)
+	}
+	if len(f.notes) != 1 || !strings.Contains(f.notes[0]["body"].(string), satisfiedMsg) {
+		t.Errorf("notes = %v, want the clabot note edited to %q", f.notes, satisfiedMsg)
+	}
+}
+
+func TestGitLabDispatchNoteCommand(t *testing.T) {
+	f, srv := newFakeGitLab(t)
+	f.files[".github/signers.txt"] = "octocat\noctocat@example.com\n"
+	f.commits = []map[string]any{gitlabCommitJSON("octocat", "octocat@example.com")}
+	c := gitlabTestConfig(t, srv)
+	gh, err := NewClient(c)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	ev, err := ParseForgeEvent(c.Forge, gitlabNoteHook, "testdata/note_hook.json")
+	if err != nil {
+		t.Fatalf("ParseForgeEvent: %v", err)
+	}
+	res, err := Dispatch(context.Background(), gh, c, ev)
+	if err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	if res.State != ResultSigned {
+		t.Errorf("state = %v, want signed", res.State)
+	}
+	if len(f.statuses) == 0 || f.statuses[len(f.statuses)-1]["state"] != "success" {
+		t.Errorf("statuses = %v, want success last", f.statuses)
+	}
+}
+
+func TestParseGitLabWebHook(t *testing.T) {
+	tests := []struct {
+		name       string
+		event      string
+		payload    string
+		wantAction string // "" for an ignored event
+	}{
+		{name: "open", event: gitlabMergeRequestHook, payload: `{"object_attributes":{"iid":7,"action":"open"}}`, wantAction: "opened"},
+		{name: "push", event: gitlabMergeRequestHook, payload: `{"object_attributes":{"iid":7,"action":"update","oldrev":"abc"}}`, wantAction: "synchronize"},
+		{name: "marked ready", event: gitlabMergeRequestHook, payload: `{"object_attributes":{"iid":7,"action":"update"},"changes":{"draft":{"previous":true,"current":false}}}`, wantAction: "ready_for_review"},
+		{name: "marked draft", event: gitlabMergeRequestHook, payload: `{"object_attributes":{"iid":7,"action":"update"},"changes":{"draft":{"previous":false,"current":true}}}`, wantAction: "converted_to_draft"},
+		{name: "retitled", event: gitlabMergeRequestHook, payload: `{"object_attributes":{"iid":7,"action":"update"},"changes":{"title":{}}}`, wantAction: "edited"},
+		{name: "merged", event: gitlabMergeRequestHook, payload: `{"object_attributes":{"iid":7,"action":"merge"}}`, wantAction: "closed"},
+		{name: "note", event: gitlabNoteHook, payload: `{"object_attributes":{"id":3,"note":"hi","noteable_type":"MergeRequest","action":"create"},"merge_request":{"iid":7}}`, wantAction: "created"},
+		{name: "edited note", event: gitlabNoteHook, payload: `{"object_attributes":{"id":3,"note":"hi","noteable_type":"MergeRequest","action":"update"},"merge_request":{"iid":7}}`, wantAction: "edited"},
+		{name: "issue note", event: gitlabNoteHook, payload: `{"object_attributes":{"id":3,"note":"hi","noteable_type":"Issue"}}`},
+		{name: "push hook", event: "Push Hook", payload: `{}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev, err := parseWebHook(forgeGitLab, tt.event, []byte(tt.payload))
+			if err != nil {
+				t.Fatalf("parseWebHook: %v", err)
+			}
+			var action string
+			var number int
+			switch ev := ev.(type) {
+			case nil:
+			case *github.PullRequestEvent:
+				action, number = ev.GetAction(), ev.GetPullRequest().GetNumber()
+			case *github.IssueCommentEvent:
+				if !ev.GetIssue().IsPullRequest() {
+					t.Error("note event is not on a pull request")
+				}
+				action, number = ev.GetAction(), ev.GetIssue().GetNumber()
+			default:
+				t.Fatalf("parseWebHook returned %T", ev)
+			}
+			if action != tt.wantAction {
+				t.Errorf("action = %q, want %q", action, tt.wantAction)
+			}
+			if tt.wantAction != "" && number != 7 {
+				t.Errorf("number = %d, want 7", number)
+			}
+		})
+	}
+}
+
+func TestParseGitLabWebHookRepo(t *testing.T) {
+	ev, err := parseWebHook(forgeGitLab, gitlabMergeRequestHook, []byte(`{"project":{"id":42,"path_with_namespace":"octo-org/sub/octo-repo"},"object_attributes":{"iid":7,"action":"open"}}`))
+	if err != nil {
+		t.Fatalf("parseWebHook: %v", err)
+	}
+	repo := eventRepo(ev)
+	if repo.GetOwner().GetLogin() != "octo-org/sub" || repo.GetName() != "octo-repo" {
+		t.Errorf("repo = %s / %s, want octo-org/sub / octo-repo", repo.GetOwner().GetLogin(), repo.GetName())
+	}
+	if got := gitlabProjectPath(repo.GetOwner().GetLogin(), repo.GetName()); got != "/projects/octo-org%2Fsub%2Focto-repo" {
+		t.Errorf("project path = %s", got)
+	}
+}
+
+func TestGitLabDeliveryToken(t *testing.T) {
+	c := Config{Forge: forgeGitLab, WebhookSecret: "s3cret"}
+	tests := []struct {
+		token string
+		event string
+		want  int
+	}{
+		{token: "wrong", event: gitlabMergeRequestHook, want: http.StatusUnauthorized},
+		{token: "", event: gitlabMergeRequestHook, want: http.StatusUnauthorized},
+		{token: "s3cret", event: "Push Hook", want: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(`{}`))
+		r.Header.Set("X-Gitlab-Token", tt.token)
+		r.Header.Set("X-Gitlab-Event", tt.event)
+		w := httptest.NewRecorder()
+		var inflight sync.WaitGroup
+		handleDelivery(w, r, c, nil, &inflight)
+		if w.Code != tt.want {
+			t.Errorf("token %q, %s: status %d, want %d", tt.token, tt.event, w.Code, tt.want)
+		}
+	}
+}
+
+func TestGitLabConfigRejectsGitHubOnlySettings(t *testing.T) {
+	t.Setenv("GITHUB_REPOSITORY", "octo-org/octo-repo")
+	t.Setenv("SIGNERS_PATH", ".github/signers.txt")
+	t.Setenv("FORGE", "gitlab")
+	t.Setenv("USE_CHECKS_API", "true")
+	if _, err := LoadConfig(); err == nil {
+		t.Fatal("LoadConfig accepted USE_CHECKS_API with FORGE=gitlab")
+	}
+}
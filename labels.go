@@ -0,0 +1,34 @@
+package clabot
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Values for LABEL_MODE.
+const (
+	labelOff  = "off"  // commit status only
+	labelBoth = "both" // labels alongside the commit status
+	labelOnly = "only" // labels instead of the commit status
+)
+
+// syncLabels puts the label for the result on the PR and removes the opposite
+// one, so a recheck never leaves both applied.
+func syncLabels(ctx context.Context, gh *Client, c Config, prNumber int, signed bool) {
+	if c.LabelMode == labelOff {
+		return
+	}
+	add, remove := c.LabelUnsigned, c.LabelSigned
+	if signed {
+		add, remove = remove, add
+	}
+
+	if _, _, err := gh.Issues.AddLabelsToIssue(ctx, c.RepoOwner, c.RepoName, prNumber, []string{add}); err != nil {
+		log.Error().Err(err).Int("pr", prNumber).Str("label", add).Msg("Failed to add label")
+	}
+	// Removing a label the PR doesn't have is a 404, which is fine.
+	if _, err := gh.Issues.RemoveLabelForIssue(ctx, c.RepoOwner, c.RepoName, prNumber, remove); err != nil && !isNotFound(err) {
+		log.Error().Err(err).Int("pr", prNumber).Str("label", remove).Msg("Failed to remove label")
+	}
+}
@@ -0,0 +1,121 @@
+package clabot
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+)
+
+// messageData is available to the comment and status templates.
+type messageData struct {
+	Author          string   // PR author
+	PRNumber        int      // PR number
+	UnsignedLogins  []string // everyone who still needs to sign
+	OutdatedLogins  []string // the unsigned who signed an older CLA version
+	RequiredVersion string   // REQUIRED_CLA_VERSION, if set
+	SignersURL      string   // where to sign, from CLA_SIGN_URL
+	Signed          int      // contributors who signed
+	Exempt          int      // contributors who don't need to sign
+}
+
+// sampleMessage stands in for a PR when templates are tried out at load.
+var sampleMessage = messageData{
+	Author:          "octocat",
+	PRNumber:        1,
+	UnsignedLogins:  []string{"octocat"},
+	OutdatedLogins:  []string{"octocat"},
+	RequiredVersion: "v2",
+	SignersURL:      "https://example.com/cla",
+	Signed:          1,
+	Exempt:          1,
+}
+
+var templateFuncs = template.FuncMap{
+	"join":     strings.Join,
+	"mentions": mentions,
+}
+
+// mentions renders logins as @-mentions. Git emails of commits without a
+// GitHub account are left as they are.
+func mentions(logins []string) string {
+	out := make([]string, len(logins))
+	for i, login := range logins {
+		if strings.Contains(login, "@") {
+			out[i] = login
+		} else {
+			out[i] = "@" + login
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+// plainNames renders logins as plain text, which notifies nobody. It stands
+// in for mentions when MENTION_AUTHOR is off.
+func plainNames(logins []string) string {
+	return strings.Join(logins, ", ")
+}
+
+// mention renders login for the fixed DCO and checkbox comments the way
+// templates do: as an @-mention, or as plain text without MENTION_AUTHOR.
+func mention(c Config, login string) string {
+	if !c.MentionAuthor {
+		return plainNames([]string{login})
+	}
+	return mentions([]string{login})
+}
+
+// parseMessage compiles a message template. A message without any template
+// action is taken as plain text and prefixed with mentions of everyone who
+// still needs to sign, which is how COMMENT_MSG behaved before templating.
+// Without mention, the mentions function names them in plain text instead.
+func parseMessage(name, text string, mention bool) (*template.Template, error) {
+	if !strings.Contains(text, "{{") {
+		text = "{{mentions .UnsignedLogins}} " + text
+	}
+	funcs := templateFuncs
+	if !mention {
+		funcs = template.FuncMap{"join": strings.Join, "mentions": plainNames}
+	}
+	tpl, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	// Parsing doesn't check field names; a trial render catches {{.Autor}}
+	// here instead of on the first PR.
+	if _, err := render(tpl, sampleMessage); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return tpl, nil
+}
+
+func render(tpl *template.Template, data messageData) (string, error) {
+	var b strings.Builder
+	if err := tpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// defaultFailureDesc is the commit status description when someone hasn't
+// signed, unless STATUS_FAILURE_DESC replaces it.
+const defaultFailureDesc = `CLA not signed by {{join .UnsignedLogins ", "}} ❌`
+
+// parseDescription compiles a status description template. Unlike comments,
+// descriptions get no implicit mentions; they can't notify anyone anyway. A
+// template that overflows GitHub's limit even for a single contributor is
+// rejected, since every description would be cut off.
+func parseDescription(name, text string) (*template.Template, error) {
+	tpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	desc, err := render(tpl, sampleMessage)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if n := utf8.RuneCountInString(desc); n > maxStatusDescription {
+		return nil, fmt.Errorf("%s: renders to %d characters, over the %d GitHub allows", name, n, maxStatusDescription)
+	}
+	return tpl, nil
+}
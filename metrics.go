@@ -0,0 +1,108 @@
+package clabot
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RunMetrics is the machine-readable summary of one run, written as a single
+// JSON line when the run ends.
+type RunMetrics struct {
+	Repo       string `json:"repo"`
+	Event      string `json:"event"`
+	PR         int    `json:"pr,omitempty"`
+	Result     string `json:"result"` // success, failure, skipped or error
+	Signed     int    `json:"signed"`
+	Unsigned   int    `json:"unsigned"`
+	Exempt     int    `json:"exempt"`
+	APICalls   int64  `json:"api_calls"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+
+	mu       sync.Mutex
+	apiCalls atomic.Int64
+	start    time.Time
+}
+
+type metricsKey struct{}
+
+// WithMetrics returns a context whose API calls and checks are counted in m.
+func WithMetrics(ctx context.Context, m *RunMetrics) context.Context {
+	return context.WithValue(ctx, metricsKey{}, m)
+}
+
+// metricsFrom returns the run's metrics, or nil (which records nothing) if
+// the context has none.
+func metricsFrom(ctx context.Context) *RunMetrics {
+	m, _ := ctx.Value(metricsKey{}).(*RunMetrics)
+	return m
+}
+
+// NewRunMetrics starts the metrics for one run of c.
+func NewRunMetrics(c Config) *RunMetrics {
+	return &RunMetrics{Repo: c.RepoOwner + "/" + c.RepoName, Event: c.EventName, start: time.Now()}
+}
+
+func (m *RunMetrics) apiCall() {
+	if m != nil {
+		m.apiCalls.Add(1)
+	}
+}
+
+// recordCheck stores the outcome of a PR check.
+func (m *RunMetrics) recordCheck(pr, signed, unsigned, exempt int) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.PR, m.Signed, m.Unsigned, m.Exempt = pr, signed, unsigned, exempt
+}
+
+// Write appends the summary to path, or prints it to stdout when path is
+// empty.
+func (m *RunMetrics) Write(path string, res Result, runErr error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case runErr != nil:
+		m.Result = "error"
+		m.Error = runErr.Error()
+	case res == ResultSigned:
+		m.Result = "success"
+	case res == ResultUnsigned:
+		m.Result = "failure"
+	default:
+		m.Result = "skipped"
+	}
+	m.APICalls = m.apiCalls.Load()
+	m.DurationMS = time.Since(m.start).Milliseconds()
+
+	line, err := json.Marshal(m)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to encode metrics")
+		return
+	}
+	line = append(line, '\n')
+
+	out := os.Stdout
+	if path != "" {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("Failed to open metrics file")
+			return
+		}
+		defer f.Close()
+		out = f
+	}
+	if _, err := out.Write(line); err != nil {
+		log.Warn().Err(err).Msg("Failed to write metrics")
+	}
+}
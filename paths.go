@@ -0,0 +1,138 @@
+package clabot
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/google/go-github/v58/github"
+	"github.com/rs/zerolog/log"
+)
+
+// pathRule requires the signers of its own sources from contributors whose
+// PR touches any of its paths, like a CODEOWNERS entry for CLAs. The source
+// keys mirror the top-level ones.
+type pathRule struct {
+	Paths            stringList `yaml:"paths"` // path.Match globs; "dir/**" matches everything below dir
+	SignersPath      stringList `yaml:"signers_path"`
+	GoogleSheetUrl   stringList `yaml:"google_sheet_url"`
+	SignersURL       stringList `yaml:"signers_url"`
+	SignersFileLocal stringList `yaml:"signers_file_local"`
+}
+
+// validate rejects rules that could never match or never be satisfied.
+func (r pathRule) validate() error {
+	if len(r.Paths) == 0 {
+		return fmt.Errorf("no paths")
+	}
+	for _, p := range r.Paths {
+		if _, err := path.Match(strings.TrimSuffix(p, "/**"), ""); err != nil {
+			return fmt.Errorf("path %q: %w", p, err)
+		}
+	}
+	if !r.apply(Config{}).hasSignerSources() {
+		return fmt.Errorf("paths %s have no signer sources", strings.Join(r.Paths, ","))
+	}
+	return nil
+}
+
+// matches reports whether file, a repo-relative path, is covered by r.
+func (r pathRule) matches(file string) bool {
+	for _, p := range r.Paths {
+		p = strings.TrimPrefix(p, "/")
+		if dir, ok := strings.CutSuffix(p, "/**"); ok {
+			if ok, _ := path.Match(dir, file); ok {
+				return true
+			}
+			for d := path.Dir(file); d != "."; d = path.Dir(d) {
+				if ok, _ := path.Match(dir, d); ok {
+					return true
+				}
+			}
+			continue
+		}
+		if ok, _ := path.Match(p, file); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// apply returns c with its signer sources replaced by r's.
+func (r pathRule) apply(c Config) Config {
+	c.SignersPath = r.SignersPath
+	c.GoogleSheetUrl = r.GoogleSheetUrl
+	c.SignersURL = r.SignersURL
+	c.SignersFileLocal = r.SignersFileLocal
+	return c
+}
+
+// hasSignerSources reports whether c names any place to load signers from.
+func (c Config) hasSignerSources() bool {
+	return len(c.SignersPath)+len(c.GoogleSheetUrl)+len(c.SignersURL)+len(c.SignersFileLocal) > 0
+}
+
+// requiredSigners loads every signer set a contributor to pr must appear in.
+// Each PATH_SIGNERS rule matching a changed file adds its sources; files no
+// rule matches fall back to the top-level sources. With no rules that is
+// simply the top-level set.
+func requiredSigners(ctx context.Context, gh *Client, c Config, pr *github.PullRequest) ([]SignerSet, error) {
+	ref := pr.GetBase().GetRef()
+	if len(c.PathSigners) == 0 {
+		s, err := LoadSigners(ctx, gh, c, ref)
+		return []SignerSet{s}, err
+	}
+
+	files, err := allFiles(ctx, gh, c, pr.GetNumber())
+	if err != nil {
+		return nil, fmt.Errorf("list files: %w", err)
+	}
+	// A renamed file counts under both its old and new path.
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.GetFilename())
+		if prev := f.GetPreviousFilename(); prev != "" {
+			paths = append(paths, prev)
+		}
+	}
+	matched := make([]bool, len(c.PathSigners))
+	unmatched := len(paths) == 0
+	for _, f := range paths {
+		hit := false
+		for i, r := range c.PathSigners {
+			if r.matches(f) {
+				matched[i], hit = true, true
+			}
+		}
+		unmatched = unmatched || !hit
+	}
+
+	var sets []SignerSet
+	if unmatched && c.hasSignerSources() {
+		s, err := LoadSigners(ctx, gh, c, ref)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, s)
+	}
+	for i, r := range c.PathSigners {
+		if !matched[i] {
+			continue
+		}
+		log.Info().Strs("paths", r.Paths).Msg("PR touches paths with their own CLA")
+		s, err := LoadSigners(ctx, gh, r.apply(c), ref)
+		if err != nil {
+			return nil, fmt.Errorf("path_signers %s: %w", strings.Join(r.Paths, ","), err)
+		}
+		sets = append(sets, s)
+	}
+	return sets, nil
+}
+
+// allFiles returns every file the PR changes.
+func allFiles(ctx context.Context, gh *Client, c Config, prNumber int) ([]*github.CommitFile, error) {
+	return paginate(ctx, &github.ListOptions{PerPage: 100}, func(opts *github.ListOptions) ([]*github.CommitFile, *github.Response, error) {
+		return gh.PullRequests.ListFiles(ctx, c.RepoOwner, c.RepoName, prNumber, opts)
+	})
+}
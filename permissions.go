@@ -0,0 +1,92 @@
+package clabot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v58/github"
+)
+
+// CheckPermissions reports which permissions clabot needs for c that its
+// credentials lack. Without them GitHub rejects statuses and comments, which
+// clabot only logs, so a misconfigured token looks like a bot doing nothing.
+//
+// Classic tokens are checked via their X-OAuth-Scopes header and GitHub Apps
+// via the installation's permissions. The Actions GITHUB_TOKEN and
+// fine-grained tokens can't be inspected; verified is false for them.
+func CheckPermissions(ctx context.Context, gh *Client, c Config) (missing []string, verified bool, err error) {
+	if c.usesApp() {
+		perms, err := installationPermissions(ctx, c)
+		if err != nil {
+			return nil, false, err
+		}
+		return missingPermissions(c, perms), true, nil
+	}
+
+	_, resp, err := gh.RateLimit.Get(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	header, ok := resp.Header["X-Oauth-Scopes"]
+	if !ok {
+		return nil, false, nil
+	}
+	for _, s := range strings.Split(strings.Join(header, ","), ",") {
+		if s = strings.TrimSpace(s); s == "repo" || s == "public_repo" {
+			return nil, true, nil
+		}
+	}
+	return []string{"repo scope (public_repo for public repositories)"}, true, nil
+}
+
+func installationPermissions(ctx context.Context, c Config) (*github.InstallationPermissions, error) {
+	inst, err := installation(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	return inst.GetPermissions(), nil
+}
+
+// installation fetches the configured App installation, authenticating as
+// the App itself.
+func installation(ctx context.Context, c Config) (*github.Installation, error) {
+	app, err := newAppTokenSource(c, c.AppID, c.AppInstallationID, c.AppPrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("github app: %w", err)
+	}
+	client, err := app.appClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	inst, _, err := client.Apps.GetInstallation(ctx, c.AppInstallationID)
+	if err != nil {
+		return nil, fmt.Errorf("get installation: %w", err)
+	}
+	return inst, nil
+}
+
+// missingPermissions compares an installation's permissions with what the
+// configured features call.
+func missingPermissions(c Config, perms *github.InstallationPermissions) []string {
+	var missing []string
+	need := func(name, have, level string) {
+		if have != "write" && (level == "write" || have != "read") {
+			missing = append(missing, name+": "+level)
+		}
+	}
+	if c.LabelMode != labelOnly {
+		if c.UseChecksAPI {
+			need("checks", perms.GetChecks(), "write")
+		} else {
+			need("statuses", perms.GetStatuses(), "write")
+		}
+	}
+	need("pull_requests", perms.GetPullRequests(), "write")
+	if c.SelfSign {
+		need("contents", perms.GetContents(), "write")
+	} else {
+		need("contents", perms.GetContents(), "read")
+	}
+	return missing
+}
@@ -0,0 +1,41 @@
+package clabot
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Prometheus collectors, served on /metrics in server mode. One-shot Action
+// runs record into them too, but nothing ever reads them.
+var (
+	promRegistry = prometheus.NewRegistry()
+
+	promChecks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "clabot_checks_total",
+		Help: "Handled events by result: none, signed, unsigned or error.",
+	}, []string{"result"})
+
+	promSignerLookup = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "clabot_signer_lookup_duration_seconds",
+		Help:    "Time spent loading signers from all sources.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	promAPIErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "clabot_github_api_errors_total",
+		Help: "GitHub API calls that failed after retries.",
+	})
+)
+
+func init() {
+	promRegistry.MustRegister(promChecks, promSignerLookup, promAPIErrors)
+	promRegistry.MustRegister(prometheus.NewGoCollector(), prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
+}
+
+// observeCheck counts one handled event.
+func observeCheck(res Result, err error) {
+	label := res.String()
+	if err != nil {
+		label = "error"
+	}
+	promChecks.WithLabelValues(label).Inc()
+}
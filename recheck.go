@@ -0,0 +1,129 @@
+package clabot
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/google/go-github/v58/github"
+	"github.com/rs/zerolog/log"
+)
+
+// RecheckOpen re-runs the check on every open PR of the repository, for
+// example after the signer list changed. Each PR gets RUN_TIMEOUT of its own;
+// with comments false only statuses and labels are updated. It writes a line
+// per PR and a summary to w, and returns an error if any PR failed to check.
+func RecheckOpen(ctx context.Context, gh *Client, c Config, w io.Writer, comments bool) error {
+	c = c.withRepoOverrides(c.RepoOwner, c.RepoName)
+	c.noComments = !comments
+
+	opts := &github.PullRequestListOptions{State: "open", ListOptions: github.ListOptions{PerPage: 100}}
+	prs, err := paginate(ctx, &opts.ListOptions, func(*github.ListOptions) ([]*github.PullRequest, *github.Response, error) {
+		return gh.PullRequests.List(ctx, c.RepoOwner, c.RepoName, opts)
+	})
+	if err != nil {
+		return fmt.Errorf("list pull requests: %w", err)
+	}
+
+	var flipped, unsigned, failed int
+	for _, pr := range prs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		was := currentState(ctx, gh, c, pr.GetHead().GetSHA())
+		prCtx, cancel := context.WithTimeout(ctx, c.RunTimeout)
+		res, err := HandlePullRequest(prCtx, gh, c, pr)
+		cancel()
+		if err != nil {
+			log.Error().Err(err).Int("pr", pr.GetNumber()).Msg("Recheck failed")
+			fmt.Fprintf(w, "#%d: error: %v\n", pr.GetNumber(), err)
+			failed++
+			continue
+		}
+		switch {
+		case res.State == ResultSigned && was != "success":
+			flipped++
+		case res.State == ResultUnsigned:
+			unsigned++
+		}
+		fmt.Fprintf(w, "#%d: %s\n", pr.GetNumber(), res.Description)
+	}
+
+	fmt.Fprintf(w, "Rechecked %d open PRs: %d flipped to signed, %d unsigned, %d errors\n", len(prs), flipped, unsigned, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d PRs could not be checked", failed, len(prs))
+	}
+	return nil
+}
+
+// currentState returns the state clabot last reported on sha ("success",
+// "failure", "pending"), or "" if there is none or it can't be read. With
+// STATUS_CONTEXTS it is the worst of the contexts' states, the same rule
+// checkContexts applies to their results.
+func currentState(ctx context.Context, gh *Client, c Config, sha string) string {
+	names := []string{c.StatusContext}
+	if len(c.StatusContexts) > 0 && c.Mode == modeCLA {
+		names = names[:0]
+		for _, sc := range c.StatusContexts {
+			names = append(names, sc.Context)
+		}
+	}
+
+	var states map[string]string // latest commit status per context
+	if !c.UseChecksAPI {
+		statuses, _, err := gh.Repositories.ListStatuses(ctx, c.RepoOwner, c.RepoName, sha, &github.ListOptions{PerPage: 100})
+		if err != nil {
+			return ""
+		}
+		states = make(map[string]string)
+		for _, s := range statuses {
+			if _, seen := states[s.GetContext()]; !seen {
+				states[s.GetContext()] = s.GetState() // newest first
+			}
+		}
+	}
+
+	worst := ""
+	for i, name := range names {
+		state := states[name]
+		if c.UseChecksAPI {
+			state = checkRunState(ctx, gh, c, sha, name)
+		}
+		if i == 0 || stateRank(state) > stateRank(worst) {
+			worst = state
+		}
+	}
+	return worst
+}
+
+// checkRunState returns the state of the latest check run called name on
+// sha, or "" if there is none or it can't be read.
+func checkRunState(ctx context.Context, gh *Client, c Config, sha, name string) string {
+	runs, _, err := gh.Checks.ListCheckRunsForRef(ctx, c.RepoOwner, c.RepoName, sha, &github.ListCheckRunsOptions{
+		CheckName: github.String(name),
+	})
+	if err != nil || len(runs.CheckRuns) == 0 {
+		return ""
+	}
+	if run := runs.CheckRuns[0]; run.GetStatus() == "completed" {
+		return run.GetConclusion()
+	}
+	return "pending"
+}
+
+// stateRank orders reported states from best to worst. A context with no
+// state ranks last: the PR wasn't passing on it.
+func stateRank(state string) int {
+	switch state {
+	case "success":
+		return 0
+	case "pending":
+		return 1
+	case "error":
+		return 3
+	case "":
+		return 4
+	default: // "failure" and the other check run conclusions
+		return 2
+	}
+}
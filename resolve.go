@@ -0,0 +1,94 @@
+package clabot
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v58/github"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/errgroup"
+)
+
+// emailResolver finds the GitHub login behind a commit email with the user
+// search, for commits that GitHub didn't link to an account. Only emails a
+// user made public can be found. Results, misses included, are cached for the
+// run, and it is safe for concurrent use.
+type emailResolver struct {
+	gh *Client
+
+	mu    sync.Mutex
+	cache map[string]string
+}
+
+func newEmailResolver(gh *Client) *emailResolver {
+	return &emailResolver{gh: gh, cache: make(map[string]string)}
+}
+
+// login returns the lowercased login for email, or "" if no single user has
+// it. Search failures, such as its low rate limit, count as a miss.
+func (r *emailResolver) login(ctx context.Context, email string) string {
+	key := strings.ToLower(email)
+	r.mu.Lock()
+	login, ok := r.cache[key]
+	r.mu.Unlock()
+	if ok {
+		return login
+	}
+
+	res, _, err := r.gh.Search.Users(ctx, email+" in:email", &github.SearchOptions{ListOptions: github.ListOptions{PerPage: 2}})
+	switch {
+	case err != nil:
+		log.Warn().Err(err).Str("email", email).Msg("Failed to look up login for email")
+	case res.GetTotal() == 1 && len(res.Users) == 1:
+		login = strings.ToLower(res.Users[0].GetLogin())
+		log.Info().Str("email", email).Str("login", login).Msg("Resolved commit email to login")
+	default:
+		log.Info().Str("email", email).Int("users", res.GetTotal()).Msg("Commit email doesn't resolve to a single login")
+	}
+	r.mu.Lock()
+	r.cache[key] = login
+	r.mu.Unlock()
+	return login
+}
+
+// resolveLogins gives contributors known only by email the login their first
+// resolvable email belongs to, then merges contributors that turn out to be
+// the same account.
+func resolveLogins(ctx context.Context, r *emailResolver, limit int, contributors []*Contributor) []*Contributor {
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(limit)
+	for _, ct := range contributors {
+		if ct.Login != "" {
+			continue
+		}
+		g.Go(func() error {
+			for _, email := range ct.Emails {
+				if login := r.login(gctx, email); login != "" {
+					ct.Login = login
+					break
+				}
+			}
+			return nil
+		})
+	}
+	_ = g.Wait() // misses are not errors
+
+	byLogin := make(map[string]*Contributor)
+	var out []*Contributor
+	for _, ct := range contributors {
+		if ct.Login == "" {
+			out = append(out, ct)
+			continue
+		}
+		if prev, ok := byLogin[ct.Login]; ok {
+			for _, email := range ct.Emails {
+				prev.addEmail(email)
+			}
+			continue
+		}
+		byLogin[ct.Login] = ct
+		out = append(out, ct)
+	}
+	return out
+}
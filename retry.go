@@ -0,0 +1,149 @@
+package clabot
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultMaxRetries = 3
+	maxBackoff        = time.Minute // never wait longer than this for a reset
+)
+
+// baseBackoff is the wait before the first retry, doubling after each one.
+var baseBackoff = time.Second
+
+// retryTransport retries GitHub API requests that hit a primary or secondary
+// rate limit, honoring Retry-After and rate limit resets. Transport errors
+// and 5xx responses are only retried for idempotent methods: a POST or PATCH
+// may have gone through before the connection dropped, and sending it again
+// would duplicate the comment or commit. With external set it serves other endpoints instead: a 429 is retried
+// after its Retry-After, and the calls stay out of the GitHub API metrics.
+type retryTransport struct {
+	base       http.RoundTripper
+	maxRetries int
+	external   bool
+}
+
+// externalClient is the HTTP client for endpoints that aren't GitHub, such
+// as SIGNERS_URL and AUDIT_LOG_URL.
+func externalClient(c Config) *http.Client {
+	return &http.Client{Transport: &retryTransport{base: http.DefaultTransport, maxRetries: c.MaxRetries, external: true}}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	idempotent := idempotentMethod(req.Method)
+	for attempt := 0; ; attempt++ {
+		// A RoundTripper must not modify the caller's request, so each retry
+		// sends a copy with a fresh body.
+		r := req
+		if attempt > 0 {
+			r = req.Clone(req.Context())
+			if req.Body != nil && req.Body != http.NoBody {
+				if req.GetBody == nil {
+					return nil, errors.New("retry: request body cannot be replayed")
+				}
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				r.Body = body
+			}
+		}
+
+		what := "GitHub API call"
+		if t.external {
+			what = "HTTP request"
+		} else {
+			metricsFrom(req.Context()).apiCall()
+		}
+		resp, err := t.base.RoundTrip(r)
+		if e := log.Debug(); e.Enabled() {
+			e = e.Str("method", req.Method).Str("url", req.URL.String()).Int("attempt", attempt)
+			if resp != nil {
+				e = e.Int("status", resp.StatusCode)
+			}
+			e.Err(err).Msg(what)
+		}
+		wait, retry := retryDelay(resp, err, attempt, idempotent, t.external)
+		if !retry || attempt >= t.maxRetries {
+			// A 404 is an answer (no such file, not a member), not a failure.
+			if !t.external && (err != nil || resp.StatusCode >= 400 && resp.StatusCode != http.StatusNotFound) {
+				promAPIErrors.Inc()
+			}
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		log.Warn().
+			Str("method", req.Method).
+			Str("url", req.URL.Path).
+			Int("attempt", attempt+1).
+			Dur("wait", wait).
+			Msg("Retrying " + what)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// idempotentMethod reports whether sending a request with method twice has
+// the same effect as sending it once.
+func idempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// retryDelay decides whether a response is worth retrying and how long to
+// wait first. A rate limit response means the request was not acted on, so
+// it is retried whatever the method; transport errors and 5xx responses
+// only when idempotent. GitHub's rate limit errors are only recognized from
+// GitHub.
+func retryDelay(resp *http.Response, err error, attempt int, idempotent, external bool) (time.Duration, bool) {
+	backoff := min(baseBackoff<<attempt, maxBackoff)
+	if err != nil {
+		return backoff, idempotent // transport errors are usually transient
+	}
+	if resp.StatusCode >= 500 {
+		return backoff, idempotent
+	}
+
+	if !external {
+		var abuse *github.AbuseRateLimitError
+		var rate *github.RateLimitError
+		switch err := github.CheckResponse(resp); {
+		case errors.As(err, &abuse):
+			if d := abuse.GetRetryAfter(); d > 0 {
+				return min(d, maxBackoff), true
+			}
+			return backoff, true
+		case errors.As(err, &rate):
+			if d := time.Until(rate.Rate.Reset.Time); d > 0 {
+				return min(d, maxBackoff), true
+			}
+			return backoff, true
+		}
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			return min(time.Duration(secs)*time.Second, maxBackoff), true
+		}
+		return backoff, true
+	}
+	return 0, false
+}
@@ -0,0 +1,73 @@
+package clabot
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryTransport(t *testing.T) {
+	defer func(d time.Duration) { baseBackoff = d }(baseBackoff)
+	baseBackoff = time.Millisecond
+
+	tests := []struct {
+		name      string
+		method    string
+		status    int
+		header    http.Header
+		wantCalls int32
+	}{
+		{name: "GET 502", method: http.MethodGet, status: http.StatusBadGateway, wantCalls: 3},
+		{name: "PUT 502", method: http.MethodPut, status: http.StatusBadGateway, wantCalls: 3},
+		{name: "DELETE 502", method: http.MethodDelete, status: http.StatusBadGateway, wantCalls: 3},
+		{name: "POST 502", method: http.MethodPost, status: http.StatusBadGateway, wantCalls: 1},
+		{name: "PATCH 500", method: http.MethodPatch, status: http.StatusInternalServerError, wantCalls: 1},
+		{name: "POST 429", method: http.MethodPost, status: http.StatusTooManyRequests, header: http.Header{"Retry-After": {"0"}}, wantCalls: 3},
+		{name: "POST rate limited", method: http.MethodPost, status: http.StatusForbidden, header: http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {"1"}}, wantCalls: 3},
+		{name: "POST 422", method: http.MethodPost, status: http.StatusUnprocessableEntity, wantCalls: 1},
+		{name: "GET 404", method: http.MethodGet, status: http.StatusNotFound, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				if body, _ := io.ReadAll(r.Body); r.Method != http.MethodGet && string(body) != `{"body":"hi"}` {
+					t.Errorf("attempt %d sent body %q", calls.Load(), body)
+				}
+				for k, v := range tt.header {
+					w.Header()[k] = v
+				}
+				w.WriteHeader(tt.status)
+				io.WriteString(w, `{"message":"nope"}`)
+			}))
+			defer srv.Close()
+
+			var body io.Reader
+			if tt.method != http.MethodGet {
+				body = strings.NewReader(`{"body":"hi"}`)
+			}
+			req, err := http.NewRequest(tt.method, srv.URL, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			orig := req.Body
+			tr := &retryTransport{base: http.DefaultTransport, maxRetries: 2}
+			resp, err := tr.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("RoundTrip: %v", err)
+			}
+			resp.Body.Close()
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("%d calls, want %d", got, tt.wantCalls)
+			}
+			if req.Body != orig {
+				t.Error("RoundTrip replaced the caller's request body")
+			}
+		})
+	}
+}
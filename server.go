@@ -0,0 +1,139 @@
+package clabot
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
+)
+
+// defaultListenAddr is where the server listens unless LISTEN_ADDR says
+// otherwise.
+const defaultListenAddr = ":8080"
+
+// Serve runs clabot as a webhook receiver instead of a one-shot Action. When
+// ctx is canceled it stops accepting deliveries and waits, up to RUN_TIMEOUT,
+// for the checks already running to finish.
+func Serve(ctx context.Context, c Config, gh *Client) error {
+	if c.WebhookSecret == "" {
+		return errors.New("WEBHOOK_SECRET is required in server mode")
+	}
+
+	var inflight sync.WaitGroup
+	mux := http.NewServeMux()
+	mux.HandleFunc("/webhook", func(w http.ResponseWriter, r *http.Request) {
+		handleDelivery(w, r, c, gh, &inflight)
+	})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		handleHealth(w, r, gh)
+	})
+	mux.Handle("/metrics", promhttp.HandlerFor(promRegistry, promhttp.HandlerOpts{}))
+
+	srv := &http.Server{Addr: c.ListenAddr, Handler: mux}
+	errc := make(chan error, 1)
+	go func() {
+		log.Info().Str("addr", c.ListenAddr).Msg("Listening for webhooks")
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Info().Msg("Shutting down, draining deliveries")
+	drainCtx, cancel := context.WithTimeout(context.Background(), c.RunTimeout)
+	defer cancel()
+	if err := srv.Shutdown(drainCtx); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	go func() {
+		inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-drainCtx.Done():
+		return errors.New("shutdown: deliveries still running after RUN_TIMEOUT")
+	}
+}
+
+// handleHealth answers liveness and readiness probes: 200 while the forge's
+// API is reachable with our credentials, 503 otherwise. The rate limit
+// endpoint doesn't count against the rate limit.
+func handleHealth(w http.ResponseWriter, r *http.Request, gh *Client) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	if _, _, err := gh.RateLimit.Get(ctx); err != nil {
+		log.Warn().Err(err).Msg("Health check failed")
+		http.Error(w, "github api unreachable", http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func handleDelivery(w http.ResponseWriter, r *http.Request, c Config, gh *Client, inflight *sync.WaitGroup) {
+	payload, event, delivery, err := readDelivery(r, c)
+	if err != nil {
+		log.Warn().Err(err).Msg("Rejected webhook delivery")
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	parsed, err := parseWebHook(c.Forge, event, payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo := eventRepo(parsed)
+	if repo == nil {
+		log.Info().Str("event", event).Msg("Ignored event")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	dc := c
+	dc.EventName = event
+
+	// GitHub expects a response within seconds; run the check afterwards.
+	w.WriteHeader(http.StatusAccepted)
+	inflight.Add(1)
+	go func() {
+		defer inflight.Done()
+		log.Info().Str("delivery", delivery).Str("repo", repo.GetFullName()).Str("event", event).Msg("Handling delivery")
+		ctx, cancel := context.WithTimeout(context.Background(), c.RunTimeout)
+		defer cancel()
+		res, err := Dispatch(ctx, gh, dc, parsed)
+		observeCheck(res.State, err)
+		if err != nil {
+			log.Error().Err(err).Str("delivery", delivery).Msg("clabot error")
+		}
+	}()
+}
+
+// readDelivery authenticates a webhook delivery and returns its payload,
+// event type and delivery ID. GitHub signs the payload with the secret;
+// GitLab sends the secret itself in X-Gitlab-Token.
+func readDelivery(r *http.Request, c Config) (payload []byte, event, delivery string, err error) {
+	if c.Forge != forgeGitLab {
+		payload, err = github.ValidatePayload(r, []byte(c.WebhookSecret))
+		return payload, github.WebHookType(r), github.DeliveryID(r), err
+	}
+	token := r.Header.Get("X-Gitlab-Token")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(c.WebhookSecret)) != 1 {
+		return nil, "", "", errors.New("X-Gitlab-Token does not match WEBHOOK_SECRET")
+	}
+	payload, err = io.ReadAll(io.LimitReader(r.Body, 25<<20))
+	return payload, r.Header.Get("X-Gitlab-Event"), r.Header.Get("X-Gitlab-Event-UUID"), err
+}
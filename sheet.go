@@ -0,0 +1,283 @@
+package clabot
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"mime"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"github.com/rs/zerolog/log"
+)
+
+// defaultSheetTimeout bounds each sheet fetch unless SHEET_TIMEOUT says
+// otherwise.
+const defaultSheetTimeout = 30 * time.Second
+
+// sheetClient fetches sheets. It is separate from other HTTP clients so its
+// timeout, which also covers reading the body, applies to sheets alone;
+// fetchSheet does the retrying.
+func sheetClient(c Config) *http.Client {
+	return &http.Client{Timeout: c.SheetTimeout}
+}
+
+// sheetPollBackoff is the first wait between sheet polls. It doubles after
+// every poll and is jittered so that retries from several PRs spread out.
+const sheetPollBackoff = 2 * time.Second
+
+func loadSignersFromGoogleSheet(ctx context.Context, c Config, csvURL string) (SignerSet, error) {
+	signers := NewSignerSet()
+	if csvURL == "" {
+		return signers, errors.New("csv url not provided")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, csvURL, nil)
+	if err != nil {
+		return signers, err
+	}
+	resp, err := sheetClient(c).Do(req)
+	if err != nil {
+		return signers, fmt.Errorf("%w: %w", ErrSheetUnavailable, err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return signers, fmt.Errorf("%w: google sheets returned %s", ErrSheetUnavailable, resp.Status)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return signers, fmt.Errorf("%w: google sheets returned %s", ErrSheetNotPublished, resp.Status)
+	case resp.StatusCode != http.StatusOK:
+		return signers, fmt.Errorf("google sheets returned %s", resp.Status)
+	}
+
+	var r io.Reader = resp.Body
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	// net/http only decompresses responses to requests it added
+	// Accept-Encoding to itself; proxies and .gz exports need this.
+	if resp.Header.Get("Content-Encoding") == "gzip" || mediaType == "application/gzip" || mediaType == "application/x-gzip" {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return signers, fmt.Errorf("gzip: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+		if strings.HasSuffix(mediaType, "gzip") {
+			contentType = "" // the payload's own type is unknown
+		}
+	}
+
+	body := bufio.NewReader(r)
+	if err := checkSheetResponse(contentType, body); err != nil {
+		return signers, err
+	}
+
+	signers, err = parseSheet(body, c)
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		// The deadline ran out while streaming the body.
+		return signers, fmt.Errorf("%w: %w", ErrSheetUnavailable, err)
+	}
+	if err != nil {
+		return signers, err
+	}
+
+	signers.logSigners("Google Sheet", csvURL)
+
+	return signers, nil
+}
+
+// fetchSheet loads a sheet, retrying ErrSheetUnavailable up to MaxRetries
+// times with the same backoff as GitHub API calls. Other errors won't go
+// away by themselves and are returned at once.
+func fetchSheet(ctx context.Context, c Config, url string) (SignerSet, error) {
+	for attempt := 0; ; attempt++ {
+		s, err := loadSignersFromGoogleSheet(ctx, c, url)
+		if err == nil || !errors.Is(err, ErrSheetUnavailable) || attempt >= c.MaxRetries || ctx.Err() != nil {
+			return s, err
+		}
+		wait := min(baseBackoff<<attempt, maxBackoff)
+		log.Warn().Err(err).Str("url", url).Int("attempt", attempt+1).Dur("wait", wait).Msg("Retrying Google Sheet")
+		select {
+		case <-ctx.Done():
+			return s, err
+		case <-time.After(wait):
+		}
+	}
+}
+
+// pollSheets re-evaluates a failing check until the unsigned contributors
+// show up or SHEET_POLL runs out. A published sheet lags its form by a minute
+// or more, so a signer who comments right away would otherwise still fail.
+// It returns the last evaluation.
+func pollSheets(ctx context.Context, gh *Client, c Config, pr *github.PullRequest, x exemptions, e evaluation) (evaluation, error) {
+	c.SignersCacheTTL = 0 // a cached sheet would never show the new signer
+	deadline := time.Now().Add(c.SheetPoll)
+	delay := sheetPollBackoff
+	for attempt := 1; len(e.unsigned) > 0; attempt++ {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			break
+		}
+		wait := min(delay/2+rand.N(delay), remaining)
+		log.Info().Int("attempt", attempt).Dur("wait", wait).Strs("unsigned", e.unsigned).Msg("Waiting for the sheet to list new signers")
+		select {
+		case <-ctx.Done():
+			return e, nil
+		case <-time.After(wait):
+		}
+
+		next, err := matchSigners(ctx, gh, c, pr, x)
+		if err != nil {
+			return e, err
+		}
+		e = next
+		delay *= 2
+	}
+	return e, nil
+}
+
+// checkSheetResponse rejects responses that aren't a CSV export. A sheet that
+// isn't published to the web answers with a 200 HTML sign-in page, which
+// would otherwise parse into garbage signers.
+func checkSheetResponse(contentType string, body *bufio.Reader) error {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	start, _ := body.Peek(4096) // bufio's default buffer size
+	lower := bytes.ToLower(bytes.TrimSpace(start))
+	isHTML := mediaType == "text/html" || bytes.HasPrefix(lower, []byte("<!doctype html")) || bytes.HasPrefix(lower, []byte("<html"))
+	switch {
+	case isHTML && (bytes.Contains(lower, []byte("accounts.google.com")) || bytes.Contains(lower, []byte("you need permission"))):
+		return fmt.Errorf("%w: google returned a sign-in page; publish the sheet to the web (File > Share > Publish to web) as CSV", ErrSheetNotPublished)
+	case isHTML:
+		return fmt.Errorf("%w: google returned HTML instead of CSV; check that the URL ends in export?format=csv", ErrSheetNotPublished)
+	}
+	switch mediaType {
+	case "", "text/csv", "text/plain", "application/csv", "application/octet-stream":
+		return nil
+	}
+	return fmt.Errorf("unexpected content type %q, expected CSV", contentType)
+}
+
+// utf8BOM is prepended to CSV exports by some proxies.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// parseSheet reads signers from a CSV export one row at a time, so memory
+// stays bounded by the signer set rather than the size of the sheet.
+func parseSheet(r io.Reader, c Config) (SignerSet, error) {
+	signers := NewSignerSet()
+
+	br := bufio.NewReader(r)
+	if b, err := br.Peek(len(utf8BOM)); err == nil && bytes.Equal(b, utf8BOM) {
+		_, _ = br.Discard(len(utf8BOM))
+	}
+
+	rdr := csv.NewReader(br)
+	rdr.LazyQuotes = true    // tolerate stray quotes in free-text columns
+	rdr.FieldsPerRecord = -1 // rows may be ragged
+	rdr.ReuseRecord = true   // cells are copied into the set as strings
+	row, err := rdr.Read()
+	if err == io.EOF {
+		return signers, nil
+	}
+	if err != nil {
+		return signers, err
+	}
+
+	// Columns named by header must be found in the first row.
+	loginCol, err := sheetColumn(c.SheetLoginColumn, row)
+	if err != nil {
+		return signers, fmt.Errorf("login column: %w", err)
+	}
+	emailCol, err := sheetColumn(c.SheetEmailColumn, row)
+	if err != nil {
+		return signers, fmt.Errorf("email column: %w", err)
+	}
+	versionCol, err := sheetColumn(c.SheetVersionColumn, row)
+	if err != nil {
+		return signers, fmt.Errorf("version column: %w", err)
+	}
+
+	// Skip leading header rows, but keep a first row that is real data.
+	headerNames := slices.Clone(c.SheetHeaderNames)
+	for _, spec := range []string{c.SheetLoginColumn, c.SheetEmailColumn, c.SheetVersionColumn} {
+		if _, err := strconv.Atoi(spec); err != nil {
+			headerNames = append(headerNames, spec)
+		}
+	}
+	inHeader := true
+	for i := 1; ; i++ {
+		switch {
+		case inHeader && isHeaderRow(row, loginCol, headerNames):
+		case len(row) == 0:
+			inHeader = false
+		case loginCol >= len(row):
+			inHeader = false
+			log.Warn().Int("row", i).Int("column", loginCol).Msg("Skipping short sheet row")
+		default:
+			inHeader = false
+			var version string
+			if versionCol >= 0 && versionCol < len(row) {
+				version = row[versionCol]
+			}
+			signers.addVersioned(row[loginCol], version, literalEntries)
+			if emailCol >= 0 && emailCol < len(row) && strings.Contains(row[emailCol], "@") {
+				signers.addVersioned(row[emailCol], version, literalEntries)
+			}
+		}
+
+		row, err = rdr.Read()
+		if err == io.EOF {
+			return signers, nil
+		}
+		if err != nil {
+			return signers, err
+		}
+	}
+}
+
+// isHeaderRow reports whether row looks like a header rather than a signer:
+// a cell matches one of names, or the login cell isn't a plausible login.
+func isHeaderRow(row []string, loginCol int, names []string) bool {
+	for _, cell := range row {
+		cell = strings.TrimSpace(cell)
+		for _, name := range names {
+			if name != "" && strings.EqualFold(cell, name) {
+				return true
+			}
+		}
+	}
+	// Logins and emails never contain whitespace; headers like "GitHub
+	// Username" usually do.
+	return loginCol < len(row) && strings.ContainsAny(strings.TrimSpace(row[loginCol]), " \t")
+}
+
+// sheetColumn resolves spec, either a zero-based index or a header name
+// (case-insensitive), to a column index. An empty spec resolves to -1.
+func sheetColumn(spec string, header []string) (int, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return -1, nil
+	}
+	if i, err := strconv.Atoi(spec); err == nil {
+		if i < 0 {
+			return -1, fmt.Errorf("negative index %d", i)
+		}
+		return i, nil
+	}
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), spec) {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("no header named %q", spec)
+}
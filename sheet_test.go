@@ -0,0 +1,135 @@
+package clabot
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func sortedKeys[V any](m map[string]V) []string {
+	return slices.Sorted(maps.Keys(m))
+}
+
+func TestParseSheet(t *testing.T) {
+	headers := stringList{"github", "login", "username"}
+	tests := []struct {
+		name       string
+		csv        string
+		c          Config
+		wantLogins []string
+		wantEmails []string
+	}{
+		{
+			name:       "bom before header name",
+			csv:        "\xEF\xBB\xBFGitHub Username,Email\noctocat,octocat@example.com\n",
+			c:          Config{SheetLoginColumn: "GitHub Username", SheetEmailColumn: "Email", SheetHeaderNames: headers},
+			wantLogins: []string{"octocat"},
+			wantEmails: []string{"octocat@example.com"},
+		},
+		{
+			name:       "bom before data",
+			csv:        "\xEF\xBB\xBFoctocat\nhubot\n",
+			c:          Config{SheetLoginColumn: "0", SheetHeaderNames: headers},
+			wantLogins: []string{"hubot", "octocat"},
+		},
+		{
+			name:       "quoted fields",
+			csv:        "\"2024-01-02, 10:00\",\"octocat\",\"Octo \"\"the cat\"\"\"\n",
+			c:          Config{SheetLoginColumn: "1", SheetHeaderNames: headers},
+			wantLogins: []string{"octocat"},
+		},
+		{
+			name:       "ragged rows",
+			csv:        "2024,octocat,octocat@example.com\n2024,hubot\n2024,monalisa,monalisa@example.com,extra\n",
+			c:          Config{SheetLoginColumn: "1", SheetEmailColumn: "2", SheetHeaderNames: headers},
+			wantLogins: []string{"hubot", "monalisa", "octocat"},
+			wantEmails: []string{"monalisa@example.com", "octocat@example.com"},
+		},
+		{
+			name:       "header named in SHEET_HEADER_NAMES",
+			csv:        "Timestamp,Login\n2024,octocat\n",
+			c:          Config{SheetLoginColumn: "1", SheetHeaderNames: headers},
+			wantLogins: []string{"octocat"},
+		},
+		{
+			name:       "header with whitespace in the login cell",
+			csv:        "Timestamp,Your GitHub handle\n2024,octocat\n",
+			c:          Config{SheetLoginColumn: "1", SheetHeaderNames: headers},
+			wantLogins: []string{"octocat"},
+		},
+		{
+			name:       "several header rows",
+			csv:        "Timestamp,GitHub\nWhen you signed,Your GitHub login\n2024,octocat\n",
+			c:          Config{SheetLoginColumn: "1", SheetHeaderNames: headers},
+			wantLogins: []string{"octocat"},
+		},
+		{
+			name:       "first row is data",
+			csv:        "2024,octocat\n2024,hubot\n",
+			c:          Config{SheetLoginColumn: "1", SheetHeaderNames: headers},
+			wantLogins: []string{"hubot", "octocat"},
+		},
+		{
+			name:       "header-like row after data is kept",
+			csv:        "2024,octocat\n2024,login\n",
+			c:          Config{SheetLoginColumn: "1", SheetHeaderNames: headers},
+			wantLogins: []string{"login", "octocat"},
+		},
+		{
+			name:       "row shorter than the login column",
+			csv:        "Timestamp,GitHub\n2024\n2024,octocat\n\n",
+			c:          Config{SheetLoginColumn: "1", SheetHeaderNames: headers},
+			wantLogins: []string{"octocat"},
+		},
+		{
+			name: "empty sheet",
+			csv:  "",
+			c:    Config{SheetLoginColumn: "1", SheetHeaderNames: headers},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := parseSheet(strings.NewReader(tt.csv), tt.c)
+			if err != nil {
+				t.Fatalf("parseSheet: %v", err)
+			}
+			if got := sortedKeys(s.Logins); !slices.Equal(got, tt.wantLogins) {
+				t.Errorf("logins = %q, want %q", got, tt.wantLogins)
+			}
+			if got := sortedKeys(s.Emails); !slices.Equal(got, tt.wantEmails) {
+				t.Errorf("emails = %q, want %q", got, tt.wantEmails)
+			}
+		})
+	}
+}
+
+func TestParseSheetMissingHeader(t *testing.T) {
+	c := Config{SheetLoginColumn: "GitHub", SheetHeaderNames: stringList{"github"}}
+	if _, err := parseSheet(strings.NewReader("Timestamp,Login\n2024,octocat\n"), c); err == nil {
+		t.Fatal("parseSheet accepted a login column that names no header")
+	}
+}
+
+func BenchmarkParseSheet(b *testing.B) {
+	var sb strings.Builder
+	sb.WriteString("Timestamp,GitHub,Email,Version\n")
+	for i := range 100_000 {
+		fmt.Fprintf(&sb, "2024-01-02 10:00:00,user%d,user%d@example.com,v%d\n", i, i, i%3+1)
+	}
+	csv := sb.String()
+	c := Config{SheetLoginColumn: "1", SheetEmailColumn: "2", SheetVersionColumn: "3", SheetHeaderNames: stringList{"github"}}
+
+	b.SetBytes(int64(len(csv)))
+	b.ReportAllocs()
+	for b.Loop() {
+		s, err := parseSheet(strings.NewReader(csv), c)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(s.Logins) != 100_000 {
+			b.Fatalf("%d logins, want 100000", len(s.Logins))
+		}
+	}
+}
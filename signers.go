@@ -0,0 +1,699 @@
+package clabot
+
+import (
+	"cmp"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/google/go-github/v58/github"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/errgroup"
+)
+
+// SignerSet holds the normalized identities that have signed the CLA.
+// Entries containing an "@" are email addresses and "@octocat" is the login
+// octocat. In a signers file, "@example.com" or "*@example.com" is an email
+// domain covered by a corporate CLA and "org:team-slug" (or
+// "org:other-org/team-slug") stands for the members of a team, which
+// LoadSigners expands into logins; "!octocat" in a repo signers file exempts
+// octocat from the CLA without counting them as a signer. An entry may
+// record the CLA version signed after a comma, as in "octocat,v2".
+type SignerSet struct {
+	Logins   map[string]struct{}
+	Emails   map[string]struct{}
+	Domains  map[string]struct{} // without the leading "@"
+	Teams    map[string]struct{} // "org/slug", or just "slug" for the repo owner's org
+	Exempt   map[string]struct{} // logins that need no CLA
+	Versions map[string]string   // CLA version signed by a login, email, domain or "org:" team
+
+	required string // REQUIRED_CLA_VERSION; older and unversioned entries don't match
+}
+
+// NewSignerSet returns an empty set.
+func NewSignerSet() SignerSet {
+	return SignerSet{
+		Logins:   make(map[string]struct{}),
+		Emails:   make(map[string]struct{}),
+		Domains:  make(map[string]struct{}),
+		Teams:    make(map[string]struct{}),
+		Exempt:   make(map[string]struct{}),
+		Versions: make(map[string]string),
+	}
+}
+
+// normalizeEntry strips a BOM and surrounding whitespace from a signer entry
+// and collapses internal whitespace.
+func normalizeEntry(entry string) string {
+	entry = strings.TrimPrefix(entry, "\uFEFF")
+	return strings.Join(strings.Fields(entry), " ")
+}
+
+// normalizeEmail lowercases the domain of an email address but keeps the
+// local part as written, since some mail systems treat it as case-sensitive.
+func normalizeEmail(email string) string {
+	email = strings.TrimSpace(email)
+	if i := strings.LastIndex(email, "@"); i >= 0 {
+		return email[:i] + strings.ToLower(email[i:])
+	}
+	return email
+}
+
+// entryKinds are the special entries a signer source may contain. Sheet
+// rows and SIGNERS_URL documents are often filled in by the signers
+// themselves, so anything they hold is taken as a plain login or email; only
+// files the maintainers control can name teams or whole domains, and only the
+// repo's own signers files, reviewed like code, can grant exemptions.
+type entryKinds uint8
+
+const (
+	teamEntries   entryKinds = 1 << iota // "org:team"
+	domainEntries                        // "@example.com", "*@example.com"
+	exemptEntries                        // "!login"
+
+	literalEntries entryKinds = 0                           // sheets and SIGNERS_URL
+	fileEntries               = teamEntries | domainEntries // SIGNERS_FILE_LOCAL
+	repoEntries               = fileEntries | exemptEntries // SIGNERS_PATH
+)
+
+func (s SignerSet) add(entry string, kinds entryKinds) {
+	entry, version, _ := strings.Cut(entry, ",")
+	s.addVersioned(entry, version, kinds)
+}
+
+// addVersioned adds entry as signed at CLA version, which may be empty.
+// Special entries the source may not contain are ignored.
+func (s SignerSet) addVersioned(entry, version string, kinds entryKinds) {
+	entry = normalizeEntry(entry)
+	version = strings.TrimSpace(version)
+	lower := strings.ToLower(entry)
+	if rest, ok := strings.CutPrefix(lower, "@"); ok && !strings.Contains(rest, ".") {
+		lower = rest // "@octocat" copied from a mention
+	}
+	key := lower
+	switch {
+	case lower == "":
+	case strings.HasPrefix(lower, "!"):
+		if kinds&exemptEntries == 0 {
+			log.Warn().Str("entry", lower).Msg("Ignoring exemption outside the repo signers file")
+			break
+		}
+		login := strings.TrimPrefix(strings.TrimSpace(lower[1:]), "@")
+		if login == "" || strings.ContainsAny(login, "@. ") {
+			log.Warn().Str("entry", lower).Msg("Ignoring exemption that is not a login")
+			break
+		}
+		s.Exempt[login] = struct{}{}
+	case strings.HasPrefix(lower, "org:"):
+		if kinds&teamEntries == 0 {
+			log.Warn().Str("entry", lower).Msg("Ignoring team entry outside a signers file")
+			break
+		}
+		if team := strings.TrimSpace(lower[len("org:"):]); team != "" {
+			s.Teams[team] = struct{}{}
+			key = "org:" + team
+		}
+	case strings.HasPrefix(lower, "*@") || strings.HasPrefix(lower, "@"):
+		if kinds&domainEntries == 0 {
+			log.Warn().Str("entry", lower).Msg("Ignoring domain entry outside a signers file")
+			break
+		}
+		key = strings.TrimPrefix(lower, "*")[1:]
+		s.Domains[key] = struct{}{}
+	case strings.Contains(lower, "@"):
+		key = normalizeEmail(entry)
+		s.Emails[key] = struct{}{}
+	case strings.Contains(lower, "."):
+		// GitHub logins can't contain dots, so this is most likely a domain
+		// missing its "@"; don't let it silently match nothing.
+		log.Warn().Str("entry", lower).Msg("Ignoring signer entry that is not a login; write corporate domains as @" + lower)
+	default:
+		s.Logins[lower] = struct{}{}
+	}
+	if version != "" {
+		s.setVersion(key, version)
+	}
+}
+
+// setVersion records version for key unless a newer one is already recorded.
+func (s SignerSet) setVersion(key, version string) {
+	if old, ok := s.Versions[key]; !ok || compareVersions(version, old) > 0 {
+		s.Versions[key] = version
+	}
+}
+
+// current reports whether the entry key satisfies the required CLA version.
+func (s SignerSet) current(key string) bool {
+	if s.required == "" {
+		return true
+	}
+	v, ok := s.Versions[key]
+	return ok && compareVersions(v, s.required) >= 0
+}
+
+// compareVersions orders CLA versions like "v2" or "1.1" by their numeric
+// parts, falling back to string order for parts that aren't numbers.
+func compareVersions(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(strings.ToLower(a), "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(strings.ToLower(b), "v"), ".")
+	for i := range max(len(as), len(bs)) {
+		var x, y string
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		xn, xerr := strconv.Atoi(x)
+		yn, yerr := strconv.Atoi(y)
+		if x == "" {
+			xerr = nil
+		}
+		if y == "" {
+			yerr = nil
+		}
+		if xerr == nil && yerr == nil {
+			if c := cmp.Compare(xn, yn); c != 0 {
+				return c
+			}
+			continue
+		}
+		if c := strings.Compare(x, y); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// merge adds o's entries to s and returns how many s already had. An entry
+// listed at several versions keeps the newest.
+func (s SignerSet) merge(o SignerSet) (dups int) {
+	for k, v := range o.Versions {
+		s.setVersion(k, v)
+	}
+	return mergeEntries(s.Logins, o.Logins) + mergeEntries(s.Emails, o.Emails) + mergeEntries(s.Domains, o.Domains) + mergeEntries(s.Teams, o.Teams) + mergeEntries(s.Exempt, o.Exempt)
+}
+
+func mergeEntries(dst, src map[string]struct{}) (dups int) {
+	for k := range src {
+		if _, ok := dst[k]; ok {
+			log.Debug().Str("entry", k).Msg("Signer listed by more than one source")
+			dups++
+		}
+		dst[k] = struct{}{}
+	}
+	return dups
+}
+
+// size is the number of entries of all kinds.
+func (s SignerSet) size() int {
+	return len(s.Logins) + len(s.Emails) + len(s.Domains) + len(s.Teams) + len(s.Exempt)
+}
+
+// logSigners logs every entry; from names the file or URL they came from.
+func (s SignerSet) logSigners(source, from string) {
+	for k := range s.Logins {
+		log.Info().Str("signer", k).Str("from", from).Msg(source + " CLA signer")
+	}
+	for k := range s.Emails {
+		log.Info().Str("email", k).Str("from", from).Msg(source + " CLA signer")
+	}
+	for k := range s.Domains {
+		log.Info().Str("domain", k).Str("from", from).Msg(source + " CLA signer")
+	}
+	for k := range s.Teams {
+		log.Info().Str("team", k).Str("from", from).Msg(source + " CLA signer")
+	}
+	for k := range s.Exempt {
+		log.Info().Str("login", k).Str("from", from).Msg(source + " CLA exemption")
+	}
+}
+
+// Signed reports whether the contributor's login, or with emailMatch one of
+// their commit emails or its domain, is in the set. foldCase also ignores case
+// in the local part of emails.
+func (s SignerSet) Signed(ct *Contributor, emailMatch, foldCase bool) bool {
+	_, ok := s.match(ct, emailMatch, foldCase)
+	return ok
+}
+
+// match is Signed, also saying which entry matched, e.g. "login octocat" or
+// "corporate domain example.com".
+func (s SignerSet) match(ct *Contributor, emailMatch, foldCase bool) (string, bool) {
+	return s.lookup(ct, emailMatch, foldCase, s.current)
+}
+
+// outdated reports whether the contributor only signed a CLA version older
+// than the required one.
+func (s SignerSet) outdated(ct *Contributor, emailMatch, foldCase bool) bool {
+	if _, ok := s.lookup(ct, emailMatch, foldCase, func(string) bool { return true }); !ok {
+		return false
+	}
+	_, ok := s.match(ct, emailMatch, foldCase)
+	return !ok
+}
+
+// lookup finds the first entry matching the contributor that accept lets
+// through, and says which one it was.
+func (s SignerSet) lookup(ct *Contributor, emailMatch, foldCase bool, accept func(key string) bool) (string, bool) {
+	if _, ok := s.Logins[ct.Login]; ok && ct.Login != "" && accept(ct.Login) {
+		return "login " + ct.Login, true
+	}
+	if !emailMatch {
+		return "", false
+	}
+	for _, email := range ct.Emails {
+		if login, ok := noreplyLogin(strings.ToLower(email)); ok {
+			// Noreply addresses only count through the login they encode.
+			if _, ok := s.Logins[login]; ok && accept(login) {
+				return "noreply email of login " + login, true
+			}
+			continue
+		}
+		if key, ok := s.hasEmail(email, foldCase); ok && accept(key) {
+			return "email " + email, true
+		}
+		if _, domain, ok := strings.Cut(email, "@"); ok {
+			if _, ok := s.Domains[domain]; ok && accept(domain) {
+				return "corporate domain " + domain, true
+			}
+		}
+	}
+	return "", false
+}
+
+const noreplyDomain = "@users.noreply.github.com"
+
+// hasEmail reports whether email is a signer, returning the entry it matched.
+// Domains are always compared case-insensitively; with foldCase the local
+// part is too.
+func (s SignerSet) hasEmail(email string, foldCase bool) (string, bool) {
+	if _, ok := s.Emails[email]; ok {
+		return email, true
+	}
+	if foldCase {
+		for e := range s.Emails {
+			if strings.EqualFold(e, email) {
+				return e, true
+			}
+		}
+	}
+	return "", false
+}
+
+// noreplyLogin extracts the login from a GitHub noreply address, which is
+// either "login@users.noreply.github.com" or "id+login@users.noreply.github.com".
+func noreplyLogin(email string) (string, bool) {
+	local, ok := strings.CutSuffix(email, noreplyDomain)
+	if !ok {
+		return "", false
+	}
+	if _, login, found := strings.Cut(local, "+"); found {
+		local = login
+	}
+	return local, local != ""
+}
+
+func loadSignersGithub(ctx context.Context, gh *Client, c Config, path, ref string) (SignerSet, error) {
+	set := NewSignerSet()
+	owner, name := c.signersRepo()
+	file, _, _, err := gh.Repositories.GetContents(ctx, owner, name, path, &github.RepositoryContentGetOptions{Ref: ref})
+	if err != nil {
+		return set, err
+	}
+
+	s, err := file.GetContent()
+	if err != nil {
+		return set, err
+	}
+
+	set, err = parseSignersFile(s, c.SignersFormat, repoEntries)
+	if err != nil {
+		return set, fmt.Errorf("parse %s: %w", path, err)
+	}
+	set.logSigners("Github", path)
+
+	return set, nil
+}
+
+// loadSignersLocal reads a signers file from the local filesystem, e.g. one
+// provisioned by configuration management on a self-hosted runner. Unlike a
+// repo file, a missing local file is an error: it was configured explicitly.
+func loadSignersLocal(path, format string) (SignerSet, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return NewSignerSet(), fmt.Errorf("%w: local file %s does not exist", ErrSignersFileNotFound, path)
+	}
+	if err != nil {
+		return NewSignerSet(), fmt.Errorf("read local signers file %s: %w", path, err)
+	}
+	if !utf8.Valid(data) {
+		return NewSignerSet(), fmt.Errorf("parse local signers file %s: not UTF-8 text", path)
+	}
+
+	set, err := parseSignersFile(string(data), format, fileEntries)
+	if err != nil {
+		return set, fmt.Errorf("parse local signers file %s: %w", path, err)
+	}
+	set.logSigners("Local file", path)
+	return set, nil
+}
+
+// Signers file formats for SIGNERS_FORMAT.
+const (
+	signersFormatPlain = "plain" // one entry per line, optionally ",version"
+	signersFormatCSV   = "csv"   // login first, then any metadata columns
+)
+
+// parseSignersFile reads a signers file in format, accepting the special
+// entries in kinds. A plain file has one login or email per line, optionally
+// followed by ",version", with blank lines and "#" comments ignored.
+func parseSignersFile(s, format string, kinds entryKinds) (SignerSet, error) {
+	if format == signersFormatCSV {
+		return parseSignersCSV(s, kinds)
+	}
+	set := NewSignerSet()
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			set.add(line, kinds)
+		}
+	}
+	return set, nil
+}
+
+// parseSignersCSV reads a signers file kept as a registry, such as
+// "login,name,company,date": the first column is the login or email and the
+// rest is for humans. A header row starting with "login" is skipped, and its
+// "version" column, if any, holds the CLA version each row signed.
+func parseSignersCSV(s string, kinds entryKinds) (SignerSet, error) {
+	set := NewSignerSet()
+	rdr := csv.NewReader(strings.NewReader(s))
+	rdr.Comment = '#'
+	rdr.FieldsPerRecord = -1 // metadata may be missing on older rows
+	rdr.TrimLeadingSpace = true
+	versionCol := -1
+	for first := true; ; first = false {
+		row, err := rdr.Read()
+		if err == io.EOF {
+			return set, nil
+		}
+		if err != nil {
+			return set, err
+		}
+		if first && strings.EqualFold(strings.TrimSpace(row[0]), "login") {
+			versionCol = slices.IndexFunc(row, func(h string) bool { return strings.EqualFold(strings.TrimSpace(h), "version") })
+			continue
+		}
+		var version string
+		if versionCol > 0 && versionCol < len(row) {
+			version = row[versionCol]
+		}
+		set.addVersioned(row[0], version, kinds)
+	}
+}
+
+// signersFileLogin returns the lowercased login or email a signers file line
+// starts with, ignoring a version or metadata columns after it.
+func signersFileLogin(line string) string {
+	entry, _, _ := strings.Cut(line, ",")
+	entry = strings.Trim(normalizeEntry(entry), `"`)
+	return strings.TrimPrefix(strings.ToLower(entry), "@")
+}
+
+// signersFileRef returns the ref to read SignersPath at for a PR based on
+// base. An empty ref reads the default branch, which is where a central
+// SIGNERS_REPO keeps its list regardless of the PR's branches.
+func signersFileRef(c Config, base string) string {
+	switch {
+	case c.SignersRef != "":
+		return c.SignersRef
+	case c.SignersRepo != "":
+		return ""
+	}
+	return base
+}
+
+// LoadSigners merges the signers from every configured sheet, endpoint, repo
+// file and local file, reading repo files at the ref signersFileRef picks for ref.
+func LoadSigners(ctx context.Context, gh *Client, c Config, ref string) (SignerSet, error) {
+	return loadSigners(ctx, gh, c, ref, nil)
+}
+
+// loadSigners is LoadSigners, additionally telling report, if non-nil, how
+// many entries each source contributed.
+func loadSigners(ctx context.Context, gh *Client, c Config, ref string, report func(source string, n int)) (SignerSet, error) {
+	ref = signersFileRef(c, ref)
+	merged := NewSignerSet()
+	// Without a source every contributor fails, which looks like the bot
+	// working rather than like the misconfiguration it is.
+	if !c.hasSignerSources() && !c.AllowEmptySigners {
+		return merged, ErrNoSignerSource
+	}
+	cache := openSignerCache(c)
+	defer cache.save()
+
+	// add merges one source's signers, reporting what it contributed.
+	add := func(source string, m SignerSet) error {
+		if m.size() == 0 && c.StrictSigners {
+			return fmt.Errorf("%s returned no signers (SIGNERS_STRICT)", source)
+		}
+		dups := merged.merge(m)
+		log.Info().Str("source", source).Int("signers", m.size()).Int("duplicates", dups).Msg("Loaded signers")
+		if report != nil {
+			report(source, m.size())
+		}
+		return nil
+	}
+
+	for _, url := range c.GoogleSheetUrl {
+		if m, ok := cache.get(sheetCacheKey(url), ""); ok {
+			if err := add(url, m); err != nil {
+				return merged, err
+			}
+			continue
+		}
+		m, err := fetchSheet(ctx, c, url)
+		if err != nil {
+			return merged, fmt.Errorf("sheet %s: %w", url, err)
+		}
+		cache.put(sheetCacheKey(url), "", m)
+		if err := add(url, m); err != nil {
+			return merged, err
+		}
+	}
+
+	for _, url := range c.SignersURL {
+		if m, ok := cache.get(urlCacheKey(url), ""); ok {
+			if err := add(url, m); err != nil {
+				return merged, err
+			}
+			continue
+		}
+		m, err := loadSignersFromURL(ctx, c, url)
+		if err != nil {
+			return merged, fmt.Errorf("signers url %s: %w", url, err)
+		}
+		cache.put(urlCacheKey(url), "", m)
+		if err := add(url, m); err != nil {
+			return merged, err
+		}
+	}
+
+	for _, path := range c.SignersPath {
+		var sha string
+		if cache != nil {
+			var err error
+			if sha, err = fileSHA(ctx, gh, c, path, ref); err != nil && !isNotFound(err) {
+				log.Warn().Err(err).Str("path", path).Msg("Failed to look up signers file SHA")
+			}
+			if m, ok := cache.get(fileCacheKey(c, path, ref), sha); ok && sha != "" {
+				if err := add(path, m); err != nil {
+					return merged, err
+				}
+				continue
+			}
+		}
+
+		m, err := loadSignersGithub(ctx, gh, c, path, ref)
+		if isNotFound(err) && c.SignersRef != "" {
+			// GetContents 404s for a missing ref too; tell the two apart.
+			owner, name := c.signersRepo()
+			if _, _, rerr := gh.Repositories.GetCommitSHA1(ctx, owner, name, ref, ""); isNotFound(rerr) {
+				return merged, fmt.Errorf("SIGNERS_REF %q does not exist in %s/%s", ref, owner, name)
+			}
+		}
+		if isNotFound(err) && c.RequireSignersFile {
+			return merged, fmt.Errorf("%w: %s at %s (REQUIRE_SIGNERS_FILE)", ErrSignersFileNotFound, path, ref)
+		}
+		if isNotFound(err) {
+			// A component's file may simply not exist yet, e.g. on a fresh
+			// setup that only uses a sheet so far.
+			log.Warn().Str("path", path).Str("ref", ref).Msg("Signers file not found, skipping")
+			continue
+		}
+		if err != nil {
+			return merged, fmt.Errorf("repo file %s: %w", path, err)
+		}
+		if sha != "" {
+			cache.put(fileCacheKey(c, path, ref), sha, m)
+		}
+		if err := add(path, m); err != nil {
+			return merged, err
+		}
+	}
+
+	for _, path := range c.SignersFileLocal {
+		m, err := loadSignersLocal(path, c.SignersFormat)
+		if err != nil {
+			return merged, err
+		}
+		if err := add(path, m); err != nil {
+			return merged, err
+		}
+	}
+
+	if err := expandTeams(ctx, gh, c, merged); err != nil {
+		return merged, err
+	}
+	merged.required = c.RequiredCLAVersion
+	log.Info().Int("unique", merged.size()).Msg("Signers loaded from all sources")
+	return merged, nil
+}
+
+// expandTeams adds the members of every team entry to the logins. Teams are
+// resolved on each run rather than cached, so membership changes apply
+// immediately. Listing members needs a token with read:org.
+func expandTeams(ctx context.Context, gh *Client, c Config, s SignerSet) error {
+	// Teams are listed concurrently; mu guards the merge into s.
+	var mu sync.Mutex
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(c.LookupConcurrency)
+	for team := range s.Teams {
+		org, slug, ok := strings.Cut(team, "/")
+		if !ok {
+			org, slug = c.RepoOwner, team
+		}
+		g.Go(func() error {
+			opts := &github.TeamListTeamMembersOptions{ListOptions: github.ListOptions{PerPage: 100}}
+			users, err := paginate(gctx, &opts.ListOptions, func(*github.ListOptions) ([]*github.User, *github.Response, error) {
+				return gh.Teams.ListTeamMembersBySlug(gctx, org, slug, opts)
+			})
+			if err != nil {
+				return fmt.Errorf("team %s/%s: %w", org, slug, err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			version, versioned := s.Versions["org:"+team]
+			for _, u := range users {
+				login := strings.ToLower(u.GetLogin())
+				s.Logins[login] = struct{}{}
+				if versioned {
+					s.setVersion(login, version)
+				}
+			}
+			log.Info().Str("org", org).Str("team", slug).Msg("Expanded team signers")
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// Contributor is one identity that must have signed the CLA.
+type Contributor struct {
+	Login  string   // lowercased GitHub login; empty when no account is linked
+	Emails []string // git emails seen on this identity's commits, domains lowercased
+}
+
+// name identifies the contributor in statuses and comments.
+func (ct *Contributor) name() string {
+	if ct.Login != "" || len(ct.Emails) == 0 {
+		return ct.Login
+	}
+	return ct.Emails[0]
+}
+
+func (ct *Contributor) addEmail(email string) {
+	email = normalizeEmail(email)
+	if email == "" || slices.Contains(ct.Emails, email) {
+		return
+	}
+	ct.Emails = append(ct.Emails, email)
+}
+
+// Values for CHECK_SCOPE.
+const (
+	scopePRAuthor      = "pr-author"
+	scopeCommitAuthors = "all-commit-authors"
+	scopeCoAuthors     = "co-authors"
+)
+
+// Values for CHECK_IDENTITY.
+const (
+	identityAuthor    = "author"
+	identityCommitter = "committer"
+	identityBoth      = "both"
+)
+
+// coAuthorRe matches a "Co-authored-by: Name <email>" trailer.
+var coAuthorRe = regexp.MustCompile(`(?im)^co-authored-by:\s*(.*?)\s*<([^>]+)>\s*$`)
+
+// CollectContributors returns the distinct identities that must have signed
+// under scope: the PR author, plus each commit's author, committer or both
+// (per identity) unless scope is scopePRAuthor, plus Co-authored-by trailers
+// with scopeCoAuthors. Identities not linked to a GitHub account are keyed by
+// their git email.
+func CollectContributors(author string, commits []*github.RepositoryCommit, scope, identity string) []*Contributor {
+	byKey := make(map[string]*Contributor)
+	var out []*Contributor
+	add := func(login, email string) {
+		login = strings.ToLower(strings.TrimSpace(login))
+		key := login
+		if key == "" {
+			key = strings.ToLower(strings.TrimSpace(email))
+		}
+		if key == "" {
+			return
+		}
+		ct, ok := byKey[key]
+		if !ok {
+			ct = &Contributor{Login: login}
+			byKey[key] = ct
+			out = append(out, ct)
+		}
+		ct.addEmail(email)
+	}
+
+	add(author, "")
+	if scope == scopePRAuthor {
+		return out
+	}
+	for _, rc := range commits {
+		if identity != identityCommitter {
+			add(rc.GetAuthor().GetLogin(), rc.GetCommit().GetAuthor().GetEmail())
+		}
+		if identity != identityAuthor {
+			add(rc.GetCommitter().GetLogin(), rc.GetCommit().GetCommitter().GetEmail())
+		}
+		if scope != scopeCoAuthors {
+			continue
+		}
+		for _, m := range coAuthorRe.FindAllStringSubmatch(rc.GetCommit().GetMessage(), -1) {
+			// Trailers carry no login, except inside a noreply address.
+			email := normalizeEmail(m[2])
+			login, _ := noreplyLogin(strings.ToLower(email))
+			add(login, email)
+		}
+	}
+	return out
+}
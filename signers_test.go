@@ -0,0 +1,116 @@
+package clabot
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/go-github/v58/github"
+)
+
+func TestSignerSetAddNormalizesLogins(t *testing.T) {
+	for _, entry := range []string{"octocat", "@Octocat ", " octocat", "OCTOCAT", "\t@octocat\r", "\uFEFFoctocat"} {
+		t.Run(entry, func(t *testing.T) {
+			s := NewSignerSet()
+			s.add(entry, repoEntries)
+			if got := sortedKeys(s.Logins); !slices.Equal(got, []string{"octocat"}) {
+				t.Errorf("logins = %q, want [octocat]", got)
+			}
+			if !s.Signed(&Contributor{Login: "octocat"}, false, false) {
+				t.Error("octocat not signed")
+			}
+			if s.Signed(&Contributor{Login: "hubot"}, false, false) {
+				t.Error("hubot signed")
+			}
+		})
+	}
+}
+
+func TestSignerSetAddEntries(t *testing.T) {
+	tests := []struct {
+		entry   string
+		kinds   entryKinds
+		logins  []string
+		emails  []string
+		domains []string
+		teams   []string
+		exempt  []string
+		version string // recorded for the entry's key
+	}{
+		{entry: "@octocat,v2", kinds: literalEntries, logins: []string{"octocat"}, version: "v2"},
+		{entry: "octocat@Example.com", kinds: literalEntries, emails: []string{"octocat@example.com"}},
+		{entry: "@example.com", kinds: fileEntries, domains: []string{"example.com"}},
+		{entry: "*@Example.com", kinds: fileEntries, domains: []string{"example.com"}},
+		{entry: "@example.com", kinds: literalEntries},
+		{entry: "org:Octo-Org/Reviewers", kinds: fileEntries, teams: []string{"octo-org/reviewers"}},
+		{entry: "org:reviewers", kinds: literalEntries},
+		{entry: "!@Dependabot", kinds: repoEntries, exempt: []string{"dependabot"}},
+		{entry: "!dependabot", kinds: fileEntries},
+		{entry: "example.com", kinds: repoEntries},
+		{entry: "  ", kinds: repoEntries},
+	}
+	for _, tt := range tests {
+		t.Run(tt.entry, func(t *testing.T) {
+			s := NewSignerSet()
+			s.add(tt.entry, tt.kinds)
+			for _, f := range []struct {
+				name      string
+				got, want []string
+			}{
+				{"logins", sortedKeys(s.Logins), tt.logins},
+				{"emails", sortedKeys(s.Emails), tt.emails},
+				{"domains", sortedKeys(s.Domains), tt.domains},
+				{"teams", sortedKeys(s.Teams), tt.teams},
+				{"exempt", sortedKeys(s.Exempt), tt.exempt},
+			} {
+				if !slices.Equal(f.got, f.want) {
+					t.Errorf("%s = %q, want %q", f.name, f.got, f.want)
+				}
+			}
+			if tt.version != "" && s.Versions[tt.logins[0]] != tt.version {
+				t.Errorf("version = %q, want %q", s.Versions[tt.logins[0]], tt.version)
+			}
+		})
+	}
+}
+
+func TestSignerSetEmailCase(t *testing.T) {
+	s := NewSignerSet()
+	s.add("Foo@Example.COM", literalEntries)
+	s.add("Octocat", literalEntries)
+	if got := sortedKeys(s.Emails); !slices.Equal(got, []string{"Foo@example.com"}) {
+		t.Fatalf("emails = %q, want [Foo@example.com]", got)
+	}
+
+	tests := []struct {
+		email      string
+		foldCase   bool
+		wantSigned bool
+	}{
+		{email: "Foo@Example.COM", wantSigned: true},
+		{email: "Foo@example.com", wantSigned: true},
+		{email: "foo@example.com", wantSigned: false},
+		{email: "FOO@EXAMPLE.COM", wantSigned: false},
+		{email: "foo@example.com", foldCase: true, wantSigned: true},
+		{email: "FOO@EXAMPLE.COM", foldCase: true, wantSigned: true},
+		{email: "bar@example.com", foldCase: true, wantSigned: false},
+	}
+	for _, tt := range tests {
+		commits := []*github.RepositoryCommit{{
+			Commit: &github.Commit{Author: &github.CommitAuthor{Email: github.String(tt.email)}},
+		}}
+		cts := CollectContributors("", commits, scopeCommitAuthors, identityAuthor)
+		if len(cts) != 1 {
+			t.Fatalf("%s: %d contributors, want 1", tt.email, len(cts))
+		}
+		if got := s.Signed(cts[0], true, tt.foldCase); got != tt.wantSigned {
+			t.Errorf("Signed(%s, foldCase=%v) = %v, want %v", tt.email, tt.foldCase, got, tt.wantSigned)
+		}
+	}
+
+	// Logins always match regardless of case.
+	commits := []*github.RepositoryCommit{{Author: &github.User{Login: github.String("OctoCat")}}}
+	cts := CollectContributors("", commits, scopeCommitAuthors, identityAuthor)
+	if len(cts) != 1 || !s.Signed(cts[0], false, false) {
+		t.Error("OctoCat not signed as octocat")
+	}
+}
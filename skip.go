@@ -0,0 +1,66 @@
+package clabot
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// skipDescription is the status of PRs that only touch SKIP_PATHS.
+const skipDescription = "No CLA required for docs-only changes ✔️"
+
+// validSkipPaths rejects malformed SKIP_PATHS globs.
+func validSkipPaths(globs []string) error {
+	for _, p := range globs {
+		if _, err := path.Match(strings.TrimSuffix(p, "/**"), ""); err != nil {
+			return fmt.Errorf("SKIP_PATHS %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// skipMatch reports whether file, a repo-relative path, matches any of
+// globs. A glob ending in "/**" matches everything below its directory, and
+// like in .gitignore a glob without a slash, such as "*.md", matches the file
+// name at any depth.
+func skipMatch(globs []string, file string) bool {
+	for _, p := range globs {
+		p = strings.TrimPrefix(p, "/")
+		if dir, ok := strings.CutSuffix(p, "/**"); ok {
+			for d := path.Dir(file); d != "."; d = path.Dir(d) {
+				if ok, _ := path.Match(dir, d); ok {
+					return true
+				}
+			}
+			continue
+		}
+		name := file
+		if !strings.Contains(p, "/") {
+			name = path.Base(file)
+		}
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// onlySkippedPaths reports whether every file the PR changes matches
+// SKIP_PATHS. A renamed file must match under both its old and new path, and
+// a PR without files still needs the CLA.
+func onlySkippedPaths(ctx context.Context, gh *Client, c Config, prNumber int) (bool, error) {
+	files, err := allFiles(ctx, gh, c, prNumber)
+	if err != nil {
+		return false, err
+	}
+	for _, f := range files {
+		if !skipMatch(c.SkipPaths, f.GetFilename()) {
+			return false, nil
+		}
+		if prev := f.GetPreviousFilename(); prev != "" && !skipMatch(c.SkipPaths, prev) {
+			return false, nil
+		}
+	}
+	return len(files) > 0, nil
+}
@@ -0,0 +1,49 @@
+package clabot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"github.com/rs/zerolog/log"
+)
+
+// slackTimeout bounds a Slack notification independently of the run, so a
+// Slack outage costs the check a few seconds at most.
+const slackTimeout = 5 * time.Second
+
+// notifySlack tells the signing team that pr has contributors who haven't
+// signed. Failures are logged and otherwise ignored; the CLA check never
+// depends on Slack.
+func notifySlack(ctx context.Context, c Config, pr *github.PullRequest, unsigned []string) {
+	if c.SlackWebhookURL == "" || c.DryRun {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), slackTimeout)
+	defer cancel()
+
+	text := fmt.Sprintf("CLA not signed on <%s|%s/%s#%d> by @%s. Unsigned: %s",
+		pr.GetHTMLURL(), c.RepoOwner, c.RepoName, pr.GetNumber(), pr.GetUser().GetLogin(), strings.Join(unsigned, ", "))
+	body, _ := json.Marshal(map[string]string{"text": text})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.SlackWebhookURL, bytes.NewReader(body))
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to build Slack notification")
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to notify Slack")
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Warn().Str("status", resp.Status).Msg("Slack rejected the notification")
+	}
+}
@@ -0,0 +1,36 @@
+package clabot
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// appendStepSummary adds the check result to the Actions job summary at path
+// ($GITHUB_STEP_SUMMARY). It does nothing when path is empty, as outside
+// Actions.
+func appendStepSummary(path, name, state, description, posted, contributors string) {
+	if path == "" {
+		return
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "### %s\n\n", name)
+	b.WriteString("| Result | Description | Status posted |\n|---|---|---|\n")
+	fmt.Fprintf(&b, "| %s | %s | %s |\n\n", state, description, posted)
+	if contributors != "" {
+		b.WriteString(contributors + "\n")
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err == nil {
+		_, err = f.WriteString(b.String())
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Failed to write job summary")
+	}
+}